@@ -0,0 +1,74 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "strings"
+
+type Alert byte
+
+const (
+	AlertNone Alert = iota
+	AlertNote
+	AlertTip
+	AlertImportant
+	AlertWarning
+	AlertCaution
+)
+
+var alertNames = []string{
+	"",
+	"note",
+	"tip",
+	"important",
+	"warning",
+	"caution",
+}
+
+var alertTitles = []string{
+	"",
+	"Note",
+	"Tip",
+	"Important",
+	"Warning",
+	"Caution",
+}
+
+func (a Alert) String() string {
+	if int(a) < len(alertNames) {
+		return alertNames[a]
+	}
+	return ""
+}
+
+// Title returns the heading GitHub displays for the alert.
+func (a Alert) Title() string {
+	if int(a) < len(alertTitles) {
+		return alertTitles[a]
+	}
+	return ""
+}
+
+func matchAlert(s string) Alert {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 || s[0] != '[' || s[1] != '!' || s[len(s)-1] != ']' {
+		return AlertNone
+	}
+	s = strings.ToLower(s[2 : len(s)-1])
+	for i := 1; i < len(alertNames); i++ {
+		if s == alertNames[i] {
+			return Alert(i)
+		}
+	}
+	return AlertNone
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import "testing"
+
+func TestMatchAlert(t *testing.T) {
+	type testCase struct {
+		in   string
+		want Alert
+	}
+	testCases := []testCase{
+		{"", AlertNone},
+		{"[!]", AlertNone},
+		{"[!NOTE]", AlertNote},
+		{"[!note]  ", AlertNote},
+		{"[!Tip]", AlertTip},
+		{"[!IMPORTANT]", AlertImportant},
+		{"[!WARNING]", AlertWarning},
+		{"[!CAUTION]", AlertCaution},
+		{"[!DANGER]", AlertNone},
+		{"[!NOTE] text", AlertNone},
+		{"[NOTE]", AlertNone},
+	}
+	for _, tc := range testCases {
+		got := matchAlert(tc.in)
+		if got != tc.want {
+			t.Errorf("matchAlert(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestAlert(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"> [!NOTE]\n> Useful *info*.", "<div class=\"markdown-alert markdown-alert-note\">\n<p class=\"markdown-alert-title\">Note</p>\n<p>Useful <em>info</em>.</p>\n</div>\n"},
+		{"> [!WARNING]\n>\n> a\n>\n> b", "<div class=\"markdown-alert markdown-alert-warning\">\n<p class=\"markdown-alert-title\">Warning</p>\n<p>a</p>\n<p>b</p>\n</div>\n"},
+		{"> [!NOTE]", "<blockquote>\n<p>[!NOTE]</p>\n</blockquote>\n"},
+		{"> [!OTHER]\n> x", "<blockquote>\n<p>[!OTHER]\nx</p>\n</blockquote>\n"},
+		{"> [!CAUTION] inline\n> x", "<blockquote>\n<p>[!CAUTION] inline\nx</p>\n</blockquote>\n"},
+		{"> [!NOTE]\n\n> x", "<blockquote>\n<p>[!NOTE]</p>\n</blockquote>\n<blockquote>\n<p>x</p>\n</blockquote>\n"},
+		{"> [!TIP]\n> a\n> [!NOTE]", "<div class=\"markdown-alert markdown-alert-tip\">\n<p class=\"markdown-alert-title\">Tip</p>\n<p>a\n[!NOTE]</p>\n</div>\n"},
+		{"- > [!NOTE]\n  > x", "<ul>\n<li>\n<div class=\"markdown-alert markdown-alert-note\">\n<p class=\"markdown-alert-title\">Note</p>\n<p>x</p>\n</div>\n</li>\n</ul>\n"},
+		{"> [!NOTE]\n>> nested", "<div class=\"markdown-alert markdown-alert-note\">\n<p class=\"markdown-alert-title\">Note</p>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</div>\n"},
+	}, Alerts(true))
+
+	runRenderTests(t, []renderTest{
+		{"> [!NOTE]\n> x", "<blockquote>\n<p>[!NOTE]\nx</p>\n</blockquote>\n"},
+	})
+}
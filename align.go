@@ -33,3 +33,12 @@ func (a Align) String() string {
 	}
 	return ""
 }
+
+// AlignOutput selects how the renderer writes the alignment of table cells.
+type AlignOutput byte
+
+const (
+	AlignStyle AlignOutput = iota // style="text-align:left"
+	AlignClass                    // class="align-left", see RenderOptions.AlignClassPrefix
+	AlignAttr                     // the legacy align="left" attribute
+)
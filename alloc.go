@@ -0,0 +1,257 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "sync"
+
+// Number of entries in a slab.
+const slabSize = 256
+
+// arena allocates the most common tokens of the LowAlloc mode in slabs,
+// one allocation for slabSize tokens. A slab stays alive as long as any
+// of its tokens is used. After reset the slabs are reused, so the tokens
+// allocated before must no longer be used.
+//
+// The methods may be called on a nil *arena, which allocates each token
+// on its own.
+type arena struct {
+	texts      textSlab
+	softbreaks softbreakSlab
+	paragraphs paragraphSlab
+	tokens     tokenSlab
+}
+
+// paragraph holds the three tokens of a paragraph in one slab entry.
+type paragraph struct {
+	open   ParagraphOpen
+	inline Inline
+	close  ParagraphClose
+}
+
+func (a *arena) text(t Text) *Text {
+	if a == nil {
+		return &t
+	}
+	return a.texts.alloc(t)
+}
+
+func (a *arena) softbreak() *Softbreak {
+	if a == nil {
+		return &Softbreak{}
+	}
+	return a.softbreaks.alloc()
+}
+
+func (a *arena) paragraph() (*ParagraphOpen, *Inline, *ParagraphClose) {
+	if a == nil {
+		return &ParagraphOpen{}, &Inline{}, &ParagraphClose{}
+	}
+	p := a.paragraphs.alloc()
+	return &p.open, &p.inline, &p.close
+}
+
+// copyTokens returns a copy of tokens with no spare capacity, so that
+// appending to it does not write over its neighbours in the slab.
+func (a *arena) copyTokens(tokens []Token) []Token {
+	if a == nil || len(tokens) > slabSize {
+		return append([]Token(nil), tokens...)
+	}
+	return a.tokens.copy(tokens)
+}
+
+func (a *arena) reset() {
+	if a == nil {
+		return
+	}
+	a.texts.reset()
+	a.softbreaks.reset()
+	a.paragraphs.reset()
+	a.tokens.reset()
+}
+
+type textSlab struct {
+	slabs [][]Text
+	cur   int
+}
+
+func (s *textSlab) alloc(t Text) *Text {
+	for s.cur < len(s.slabs) && len(s.slabs[s.cur]) == slabSize {
+		s.cur++
+	}
+	if s.cur == len(s.slabs) {
+		s.slabs = append(s.slabs, make([]Text, 0, slabSize))
+	}
+	slab := append(s.slabs[s.cur], t)
+	s.slabs[s.cur] = slab
+	return &slab[len(slab)-1]
+}
+
+func (s *textSlab) reset() {
+	for i, slab := range s.slabs {
+		for j := range slab {
+			slab[j] = Text{}
+		}
+		s.slabs[i] = slab[:0]
+	}
+	s.cur = 0
+}
+
+type softbreakSlab struct {
+	slabs [][]Softbreak
+	cur   int
+}
+
+func (s *softbreakSlab) alloc() *Softbreak {
+	for s.cur < len(s.slabs) && len(s.slabs[s.cur]) == slabSize {
+		s.cur++
+	}
+	if s.cur == len(s.slabs) {
+		s.slabs = append(s.slabs, make([]Softbreak, 0, slabSize))
+	}
+	slab := append(s.slabs[s.cur], Softbreak{})
+	s.slabs[s.cur] = slab
+	return &slab[len(slab)-1]
+}
+
+func (s *softbreakSlab) reset() {
+	for i, slab := range s.slabs {
+		s.slabs[i] = slab[:0]
+	}
+	s.cur = 0
+}
+
+type paragraphSlab struct {
+	slabs [][]paragraph
+	cur   int
+}
+
+func (s *paragraphSlab) alloc() *paragraph {
+	for s.cur < len(s.slabs) && len(s.slabs[s.cur]) == slabSize {
+		s.cur++
+	}
+	if s.cur == len(s.slabs) {
+		s.slabs = append(s.slabs, make([]paragraph, 0, slabSize))
+	}
+	slab := append(s.slabs[s.cur], paragraph{})
+	s.slabs[s.cur] = slab
+	return &slab[len(slab)-1]
+}
+
+func (s *paragraphSlab) reset() {
+	for i, slab := range s.slabs {
+		for j := range slab {
+			slab[j] = paragraph{}
+		}
+		s.slabs[i] = slab[:0]
+	}
+	s.cur = 0
+}
+
+type tokenSlab struct {
+	slabs [][]Token
+	cur   int
+}
+
+func (s *tokenSlab) copy(tokens []Token) []Token {
+	n := len(tokens)
+	for s.cur < len(s.slabs) && slabSize-len(s.slabs[s.cur]) < n {
+		s.cur++
+	}
+	if s.cur == len(s.slabs) {
+		s.slabs = append(s.slabs, make([]Token, 0, slabSize))
+	}
+	slab := s.slabs[s.cur]
+	start := len(slab)
+	slab = append(slab, tokens...)
+	s.slabs[s.cur] = slab
+	return slab[start:len(slab):len(slab)]
+}
+
+func (s *tokenSlab) reset() {
+	for i, slab := range s.slabs {
+		for j := range slab {
+			slab[j] = nil
+		}
+		s.slabs[i] = slab[:0]
+	}
+	s.cur = 0
+}
+
+// blockBuffers are the scratch buffer of normalizeAndIndex and the line
+// marks of a stateBlock, kept in blockPool between parses.
+type blockBuffers struct {
+	buf                                     []byte
+	bMarks, eMarks, tShift, sCount, bsCount []int
+}
+
+var blockPool = sync.Pool{
+	New: func() interface{} { return new(blockBuffers) },
+}
+
+// stateBlock is newStateBlock using the buffers, which must not be put
+// back into the pool before the parsing is done.
+func (b *blockBuffers) stateBlock(src []byte, md *Markdown, env *environment) *stateBlock {
+	if len(b.buf) < len(src)*4 {
+		b.buf = make([]byte, len(src)*4)
+	}
+	s := &stateBlock{}
+	str, bMarks, eMarks, tShift, sCount := normalizeAndIndexTo(src, b.buf, b.bMarks[:0], b.eMarks[:0], b.tShift[:0], b.sCount[:0])
+	s.index(str, bMarks, eMarks, tShift, sCount, b.bsCount[:0])
+	s.md = md
+	s.env = env
+	b.bMarks, b.eMarks, b.tShift, b.sCount, b.bsCount = s.bMarks, s.eMarks, s.tShift, s.sCount, s.bsCount
+	return s
+}
+
+var inlinePool = sync.Pool{
+	New: func() interface{} { return new(stateInline) },
+}
+
+// parsePooled is parse with the state taken from inlinePool and the
+// tokens copied into the arena.
+func (i inline) parsePooled(src string, md *Markdown, env *environment) []Token {
+	s := inlinePool.Get().(*stateInline)
+	s.src = src
+	s.md = md
+	s.env = env
+	s.posMax = len(src)
+
+	i.tokenize(s)
+	tokens := env.arena.copyTokens(s.tokens)
+
+	s.reset()
+	inlinePool.Put(s)
+
+	return tokens
+}
+
+// reset clears the state, keeping the memory of its tokens, pending
+// buffer and cache.
+func (s *stateInline) reset() {
+	for i := range s.tokens {
+		s.tokens[i] = nil
+	}
+	for pos := range s.cache {
+		delete(s.cache, pos)
+	}
+	s.pending.Reset()
+	s.delimiters = s.delimiters[:0]
+
+	s.stateCore = stateCore{tokens: s.tokens[:0]}
+	s.pos = 0
+	s.posMax = 0
+	s.level = 0
+	s.pendingLevel = 0
+	s.scan = nil
+}
@@ -0,0 +1,105 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLowAllocSpec(t *testing.T) {
+	for _, ex := range loadExamplesFromJSON(*commonMarkSpec) {
+		want, _ := render(ex.Markdown, HTML(true), XHTMLOutput(true), Linkify(false), Typographer(false))
+		got, err := render(ex.Markdown, HTML(true), XHTMLOutput(true), Linkify(false), Typographer(false), LowAlloc(true))
+		if err != nil {
+			t.Errorf("#%d (%s): PANIC (%v)", ex.Num, ex.Section, err)
+		} else if got != want {
+			t.Errorf("#%d (%s): LowAlloc mode renders %q, want %q", ex.Num, ex.Section, got, want)
+		}
+	}
+}
+
+func TestLowAllocAllocations(t *testing.T) {
+	// The race detector makes the pools drop their items at random.
+	if raceEnabled {
+		t.Skip("allocation counts are not stable under the race detector")
+	}
+
+	data := specCorpus()
+
+	md := New(HTML(true))
+	lowAlloc := New(HTML(true), LowAlloc(true))
+	allocs := testing.AllocsPerRun(5, func() { md.Parse(data) })
+	fewer := testing.AllocsPerRun(5, func() { lowAlloc.Parse(data) })
+	if fewer > allocs*0.6 {
+		t.Errorf("LowAlloc: %.0f allocations per parse, want at most 60%% of %.0f", fewer, allocs)
+	}
+}
+
+func TestDocumentReset(t *testing.T) {
+	md := New(HTML(true), LowAlloc(true))
+	d := md.ParseDocument([]byte("# Title\n\nsome *text*\nover two lines\n\n[a]\n"))
+	checkDocument(t, md, d)
+
+	d.Reset([]byte("- one\n- two\n\n[a]: /a\n\n[a] and `code`\n"))
+	checkDocument(t, md, d)
+
+	if err := d.Edit(0, 1, []byte("*")); err != nil {
+		t.Fatal(err)
+	}
+	checkDocument(t, md, d)
+
+	d.Reset(nil)
+	if tokens := d.Tokens(); len(tokens) != 0 {
+		t.Errorf("got %d tokens after Reset(nil)", len(tokens))
+	}
+}
+
+func arenaLen(a *arena) int {
+	n := 0
+	for _, slab := range a.texts.slabs {
+		n += len(slab)
+	}
+	for _, slab := range a.softbreaks.slabs {
+		n += len(slab)
+	}
+	for _, slab := range a.paragraphs.slabs {
+		n += len(slab)
+	}
+	for _, slab := range a.tokens.slabs {
+		n += len(slab)
+	}
+	return n
+}
+
+func TestDocumentEditArena(t *testing.T) {
+	md := New(LowAlloc(true))
+	d := md.ParseDocument([]byte("# Title\n\nsome *text*\nover two lines\n\n- one\n- two\n"))
+	n := arenaLen(d.arena)
+	if n == 0 {
+		t.Fatal("the parse used no slabs")
+	}
+	for i := 0; i < 100; i++ {
+		if err := d.Edit(9, 13, []byte("more")); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Edit(len(d.Source())-4, len(d.Source())-1, []byte("2")); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Edit(len(d.Source())-2, len(d.Source())-1, []byte("two")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkDocument(t, md, d)
+	if got := arenaLen(d.arena); got != n {
+		t.Errorf("the slabs hold %d entries after edits, %d before", got, n)
+	}
+}
+
+func TestArenaCopyTokens(t *testing.T) {
+	a := &arena{}
+	one := a.copyTokens([]Token{&Text{Content: "a"}})
+	two := a.copyTokens([]Token{&Text{Content: "b"}})
+	_ = append(one, &Softbreak{})
+	if !reflect.DeepEqual(two, []Token{&Text{Content: "b"}}) {
+		t.Errorf("appending to a slice from the arena changed its neighbour: %v", two)
+	}
+}
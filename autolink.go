@@ -25,19 +25,28 @@ func ruleAutolink(s *stateInline, silent bool) (_ bool) {
 
 	tail := src[pos:]
 
-	if strings.IndexByte(tail, '>') < 0 {
-		return
+	if c := s.hardened(); c != nil {
+		if c.lastGT <= pos {
+			return
+		}
+	} else {
+		gt := strings.IndexByte(tail, '>')
+		if gt < 0 {
+			s.env.steps += len(tail)
+			return
+		}
+		s.env.steps += gt
 	}
 
 	link := matchAutolink(tail)
 	if link != "" {
 		href := normalizeLink(link)
-		if !validateLink(href) {
+		if !s.md.allowsURL(href, URLAutolink) {
 			return
 		}
 
 		if !silent {
-			s.pushOpeningToken(&LinkOpen{Href: href})
+			s.pushOpeningToken(&LinkOpen{Href: s.md.rewriteURL(href, URLAutolink), Auto: true})
 			s.pushToken(&Text{Content: normalizeLinkText(link)})
 			s.pushClosingToken(&LinkClose{})
 		}
@@ -50,12 +59,12 @@ func ruleAutolink(s *stateInline, silent bool) (_ bool) {
 	email := matchEmail(tail)
 	if email != "" {
 		href := normalizeLink("mailto:" + email)
-		if !validateLink(href) {
+		if !s.md.allowsURL(href, URLAutolink) {
 			return
 		}
 
 		if !silent {
-			s.pushOpeningToken(&LinkOpen{Href: href})
+			s.pushOpeningToken(&LinkOpen{Href: s.md.rewriteURL(href, URLAutolink), Auto: true})
 			s.pushToken(&Text{Content: email})
 			s.pushClosingToken(&LinkClose{})
 		}
@@ -22,7 +22,7 @@ var (
 )
 
 func init() {
-	for _, b := range "-.abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+	for _, b := range "+-.0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
 		schemecs[b] = true
 	}
 	for i := 0x21; i <= 0xff; i++ {
@@ -36,7 +36,7 @@ func init() {
 }
 
 func matchAutolink(s string) string {
-	if len(s) < 6 || s[0] != '<' {
+	if len(s) < 5 || s[0] != '<' {
 		return ""
 	}
 
@@ -47,7 +47,7 @@ func matchAutolink(s string) string {
 		switch st {
 		case 0: // initial state
 			switch {
-			case schemecs[b]:
+			case byteutil.IsLetter(b):
 				st = 1
 				n++
 			default:
@@ -58,28 +58,16 @@ func matchAutolink(s string) string {
 			switch {
 			case schemecs[b]:
 				n++
-				if n > 23 {
-					return ""
-				}
-			case b == ':':
-				schema := byteutil.ToLower(s[1:i])
-				if !matchSchema(schema) {
+				if n > 32 {
 					return ""
 				}
+			case b == ':' && n > 1:
 				st = 2
 			default:
 				return ""
 			}
 
 		case 2: // http:
-			switch {
-			case linkcs[b]:
-				st = 3
-				break
-			default:
-				return ""
-			}
-		case 3: // http:/
 			switch {
 			case linkcs[b]:
 				break
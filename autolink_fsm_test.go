@@ -11,16 +11,21 @@ func TestMatchAutolink(t *testing.T) {
 		{"", ""},
 		{"%#!", ""},
 		{"<google.com>", ""},
-		{"<http:>", ""},
+		{"<http:>", "http:"},
 		{"<http://google.com", ""},
 		{"<http://google.com>", "http://google.com"},
 		{"<http://url with spaces>", ""},
 		{"<http://\x00>", ""},
 		{"<http:\x00>", ""},
 		{"<%#!://url>", ""},
-		{"<waytoolongstringforschema://url>", ""},
+		{"<a:b>", ""},
+		{"<1a:b>", ""},
+		{"<a+b+c:d>", "a+b+c:d"},
+		{"<MAILTO:FOO@BAR.BAZ>", "MAILTO:FOO@BAR.BAZ"},
+		{"<thirtytwobyteslongschemeforurlsx://url>", "thirtytwobyteslongschemeforurlsx://url"},
+		{"<thirtythreebyteslongschemeforurls://url>", ""},
 		{"<ws:x>", "ws:x"},
-		{"<xxx://url>", ""},
+		{"<xxx://url>", "xxx://url"},
 	}
 	for _, tc := range testCases {
 		got := matchAutolink(tc.in)
@@ -33,6 +33,18 @@ func ruleBackticks(s *stateInline, silent bool) (_ bool) {
 
 	end := pos
 
+	// In the hardened mode, the runs seen by a failed scan tell whether
+	// the later code spans can be closed at all.
+	var runs map[int]int
+	if c := s.hardened(); c != nil {
+		if c.noBacktickRun(len(marker), s.pos, max) {
+			end = max
+		} else {
+			runs = make(map[int]int)
+		}
+	}
+	from := end
+
 	for {
 		for start = end; start < max && src[start] != '`'; start++ {
 			// do nothing
@@ -47,6 +59,7 @@ func ruleBackticks(s *stateInline, silent bool) (_ bool) {
 		}
 
 		if end-start == len(marker) {
+			s.env.steps += end - from
 			if !silent {
 				s.pushToken(&CodeInline{
 					Content: normalizeInlineCode(src[pos:start]),
@@ -55,6 +68,18 @@ func ruleBackticks(s *stateInline, silent bool) (_ bool) {
 			s.pos = end
 			return true
 		}
+
+		if runs != nil {
+			runs[end-start] = start
+		}
+	}
+
+	s.env.steps += max - from
+
+	if runs != nil {
+		s.scan.backticks = runs
+		s.scan.backtickPos = s.pos
+		s.scan.backtickMax = max
 	}
 
 	if !silent {
@@ -14,12 +14,11 @@
 package markdown
 
 func ruleBlockQuote(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
-	shift := s.tShift[startLine]
-	if shift < 0 {
+	if s.sCount[startLine] < 0 {
 		return
 	}
 
-	pos := s.bMarks[startLine] + shift
+	pos := s.bMarks[startLine] + s.tShift[startLine]
 	src := s.src
 
 	if src[pos] != '>' {
@@ -30,59 +29,85 @@ func ruleBlockQuote(s *stateBlock, startLine, endLine int, silent bool) (_ bool)
 		return true
 	}
 
-	pos++
-	max := s.eMarks[startLine]
+	oldIndent := s.blkIndent
+	s.blkIndent = 0
 
-	if pos < max && src[pos] == ' ' {
-		pos++
+	var oldBMarks, oldTShift, oldSCount, oldBSCount []int
+	save := func(line int) {
+		oldBMarks = append(oldBMarks, s.bMarks[line])
+		oldTShift = append(oldTShift, s.tShift[line])
+		oldSCount = append(oldSCount, s.sCount[line])
+		oldBSCount = append(oldBSCount, s.bsCount[line])
 	}
 
-	oldIndent := s.blkIndent
-	s.blkIndent = 0
+	// cutMarker cuts the > at pos and a space after it off the line and
+	// reports whether the rest of the line is blank. A tab after the >
+	// counts as a space and the rest of its width as indentation.
+	cutMarker := func(line, pos int) bool {
+		save(line)
+		max := s.eMarks[line]
+		initial := s.sCount[line] + 1
+		pos++
 
-	oldBMarks := []int{s.bMarks[startLine]}
-	s.bMarks[startLine] = pos
+		adjustTab := false
+		spaceAfterMarker := false
+		if pos < max && src[pos] == ' ' {
+			pos++
+			initial++
+			spaceAfterMarker = true
+		} else if pos < max && src[pos] == '\t' {
+			spaceAfterMarker = true
+			if (s.bsCount[line]+initial)%4 == 3 {
+				pos++
+				initial++
+			} else {
+				adjustTab = true
+			}
+		}
+
+		offset := initial
+		s.bMarks[line] = pos
+		for ; pos < max; pos++ {
+			if b := src[pos]; b == '\t' {
+				tab := 0
+				if adjustTab {
+					tab = 1
+				}
+				offset += 4 - (offset+s.bsCount[line]+tab)%4
+			} else if b == ' ' {
+				offset++
+			} else {
+				break
+			}
+		}
 
-	if pos < max {
-		pos = s.skipSpaces(pos)
+		s.bsCount[line] = s.sCount[line] + 1
+		if spaceAfterMarker {
+			s.bsCount[line]++
+		}
+		s.sCount[line] = offset - initial
+		s.tShift[line] = pos - s.bMarks[line]
+		return pos >= max
 	}
-	lastLineEmpty := pos >= max
 
-	oldTShift := []int{s.tShift[startLine]}
-	s.tShift[startLine] = pos - s.bMarks[startLine]
+	lastLineEmpty := cutMarker(startLine, pos)
 
 	nextLine := startLine + 1
 outer:
 	for ; nextLine < endLine; nextLine++ {
-		shift := s.tShift[nextLine]
-		if shift < oldIndent {
+		if s.sCount[nextLine] < oldIndent {
 			break
 		}
 
-		pos = s.bMarks[nextLine] + shift
-		max = s.eMarks[nextLine]
+		pos = s.bMarks[nextLine] + s.tShift[nextLine]
+		max := s.eMarks[nextLine]
 
 		if pos >= max {
 			break
 		}
 
 		if src[pos] == '>' {
-			pos++
-			if pos < max && src[pos] == ' ' {
-				pos++
-			}
-
-			oldBMarks = append(oldBMarks, s.bMarks[nextLine])
-			s.bMarks[nextLine] = pos
-
-			if pos < max {
-				pos = s.skipSpaces(pos)
-			}
-			lastLineEmpty = pos >= max
-
-			oldTShift = append(oldTShift, s.tShift[nextLine])
-			s.tShift[nextLine] = pos - s.bMarks[nextLine]
-
+			lastLineEmpty = cutMarker(nextLine, pos)
 			continue
 		}
 
@@ -92,6 +117,7 @@ outer:
 
 		for _, r := range []blockRule{
 			ruleFence,
+			ruleContainer,
 			ruleHR,
 			ruleList,
 			ruleHeading,
@@ -105,28 +131,39 @@ outer:
 			}
 		}
 
-		oldBMarks = append(oldBMarks, s.bMarks[nextLine])
-		oldTShift = append(oldTShift, s.tShift[nextLine])
+		save(nextLine)
+		s.sCount[nextLine] = -1
+	}
 
-		s.tShift[nextLine] = -1
+	alert := AlertNone
+	contentStart := startLine
+	if s.md.Alerts && nextLine > startLine+1 && s.sCount[startLine+1] >= 0 {
+		pos = s.bMarks[startLine] + s.tShift[startLine]
+		alert = matchAlert(src[pos:s.eMarks[startLine]])
+		if alert != AlertNone {
+			contentStart++
+		}
 	}
 
 	oldParentType := s.parentType
 	s.parentType = ptBlockQuote
 	tok := &BlockquoteOpen{
-		Map: [2]int{startLine, 0},
+		Alert: alert,
+		Map:   [2]int{startLine, 0},
 	}
 	s.pushOpeningToken(tok)
 
-	s.md.block.tokenize(s, startLine, nextLine)
+	s.md.block.tokenize(s, contentStart, nextLine)
 
-	s.pushClosingToken(&BlockquoteClose{})
+	s.pushClosingToken(&BlockquoteClose{Alert: alert})
 	s.parentType = oldParentType
 	tok.Map[1] = s.line
 
-	for i := 0; i < len(oldTShift); i++ {
+	for i := range oldBMarks {
 		s.bMarks[startLine+i] = oldBMarks[i]
 		s.tShift[startLine+i] = oldTShift[i]
+		s.sCount[startLine+i] = oldSCount[i]
+		s.bsCount[startLine+i] = oldBSCount[i]
 	}
 	s.blkIndent = oldIndent
 
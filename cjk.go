@@ -0,0 +1,110 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// isWideRune reports whether r is an East Asian wide or fullwidth
+// character other than Hangul, i.e. a character of a script that is not
+// written with spaces between words.
+func isWideRune(r rune) bool {
+	switch {
+	case r < 0x1100:
+		return false
+	case r >= 0x2e80 && r <= 0x303e, // CJK radicals, symbols and punctuation
+		r >= 0x3041 && r <= 0x33ff, // kana, bopomofo, CJK compatibility
+		r >= 0x3400 && r <= 0x4dbf, // CJK extension A
+		r >= 0x4e00 && r <= 0x9fff, // CJK unified ideographs
+		r >= 0xa000 && r <= 0xa4cf, // Yi
+		r >= 0xf900 && r <= 0xfaff, // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f, // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60, // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x20000 && r <= 0x3fffd: // CJK extensions B and later
+		return true
+	}
+	return false
+}
+
+// isCJKRune reports whether r is a Chinese, Japanese or Korean character,
+// including Hangul and CJK punctuation.
+func isCJKRune(r rune) bool {
+	return isWideRune(r) || unicode.Is(unicode.Hangul, r)
+}
+
+func lastTextRune(tokens []Token, idx int) rune {
+	for i := idx - 1; i >= 0; i-- {
+		switch tok := tokens[i].(type) {
+		case *Text:
+			r, _ := utf8.DecodeLastRuneInString(tok.Content)
+			return r
+		case *EmphasisOpen, *EmphasisClose, *StrongOpen, *StrongClose,
+			*StrikethroughOpen, *StrikethroughClose, *LinkOpen, *LinkClose:
+			continue
+		}
+		return utf8.RuneError
+	}
+	return utf8.RuneError
+}
+
+func firstTextRune(tokens []Token, idx int) rune {
+	for i := idx + 1; i < len(tokens); i++ {
+		switch tok := tokens[i].(type) {
+		case *Text:
+			r, _ := utf8.DecodeRuneInString(tok.Content)
+			return r
+		case *EmphasisOpen, *EmphasisClose, *StrongOpen, *StrongClose,
+			*StrikethroughOpen, *StrikethroughClose, *LinkOpen, *LinkClose:
+			continue
+		}
+		return utf8.RuneError
+	}
+	return utf8.RuneError
+}
+
+// ruleCJKBreaks removes the softbreaks between two wide characters, which
+// the browsers would otherwise render as spaces.
+func ruleCJKBreaks(s *stateCore) {
+	if !s.md.CJKFriendly {
+		return
+	}
+
+	for _, tok := range s.tokens {
+		tok, ok := tok.(*Inline)
+		if !ok {
+			continue
+		}
+
+		children := tok.Children
+		var kept []Token
+		for i, child := range children {
+			if _, ok := child.(*Softbreak); ok &&
+				isWideRune(lastTextRune(children, i)) && isWideRune(firstTextRune(children, i)) {
+				if kept == nil {
+					kept = append(make([]Token, 0, len(children)), children[:i]...)
+				}
+				continue
+			}
+			if kept != nil {
+				kept = append(kept, child)
+			}
+		}
+		if kept != nil {
+			tok.Children = kept
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package markdown
+
+import "testing"
+
+func TestCJKFriendly(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"中文\n文字", "<p>中文文字</p>\n"},
+		{"日本語の\n*文章*です", "<p>日本語の<em>文章</em>です</p>\n"},
+		{"中文\nEnglish", "<p>中文\nEnglish</p>\n"},
+		{"한국어\n문장", "<p>한국어\n문장</p>\n"},
+		{"**강조**다", "<p><strong>강조</strong>다</p>\n"},
+		{"**中文**文字", "<p><strong>中文</strong>文字</p>\n"},
+		{"**「强调」**文字", "<p><strong>「强调」</strong>文字</p>\n"},
+		{"**이 용어(term)**가", "<p><strong>이 용어(term)</strong>가</p>\n"},
+		{"テスト\n**強調**。", "<p>テスト<strong>強調</strong>。</p>\n"},
+		{"中文\n\n文字", "<p>中文</p>\n<p>文字</p>\n"},
+		{"中文  \n文字", "<p>中文<br>\n文字</p>\n"},
+		{"中文\n`code`", "<p>中文\n<code>code</code></p>\n"},
+		{"中\nA\n文", "<p>中\nA\n文</p>\n"},
+	}, CJKFriendly(true))
+
+	got, _ := render("**「强调」**文字")
+	if want := "<p>**「强调」**文字</p>\n"; got != want {
+		t.Errorf("without CJKFriendly: got %q, want %q", got, want)
+	}
+}
@@ -14,7 +14,7 @@
 package markdown
 
 func ruleCode(s *stateBlock, startLine, endLine int, _ bool) (_ bool) {
-	if s.tShift[startLine]-s.blkIndent < 4 {
+	if s.sCount[startLine]-s.blkIndent < 4 {
 		return
 	}
 
@@ -27,7 +27,7 @@ func ruleCode(s *stateBlock, startLine, endLine int, _ bool) (_ bool) {
 			continue
 		}
 
-		if s.tShift[nextLine]-s.blkIndent > 3 {
+		if s.sCount[nextLine]-s.blkIndent > 3 {
 			nextLine++
 			last = nextLine
 			continue
@@ -0,0 +1,171 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"strings"
+
+	"github.com/opennota/byteutil"
+)
+
+func isContainerNameByte(b byte) bool {
+	return byteutil.IsLetter(b) || byteutil.IsDigit(b) || b == '-' || b == '_'
+}
+
+func parseContainerParams(params string) (name, title string) {
+	i := 0
+	for i < len(params) && isContainerNameByte(params[i]) {
+		i++
+	}
+	if i == 0 || (i < len(params) && params[i] != ' ') {
+		return "", ""
+	}
+	return params[:i], strings.TrimSpace(params[i:])
+}
+
+func isContainerCloser(s *stateBlock, line int) (count int) {
+	pos := s.bMarks[line] + s.tShift[line]
+	max := s.eMarks[line]
+	mem := pos
+	pos = s.skipBytes(pos, ':')
+	count = pos - mem
+	if count < 3 {
+		return 0
+	}
+	if pos = s.skipSpaces(pos); pos < max {
+		return 0
+	}
+	return count
+}
+
+func ruleContainer(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
+	if !s.md.Containers {
+		return
+	}
+
+	shift := s.sCount[startLine]
+	if shift < 0 {
+		return
+	}
+
+	pos := s.bMarks[startLine] + s.tShift[startLine]
+	max := s.eMarks[startLine]
+	src := s.src
+
+	if pos+3 > max || src[pos] != ':' {
+		return
+	}
+
+	mem := pos
+	pos = s.skipBytes(pos, ':')
+	len := pos - mem
+	if len < 3 {
+		return
+	}
+
+	name, title := parseContainerParams(strings.TrimSpace(src[pos:max]))
+	if name == "" {
+		return
+	}
+
+	if silent {
+		return true
+	}
+
+	nextLine := startLine
+	haveEndMarker := false
+	depth := 0
+
+	for {
+		nextLine++
+		if nextLine >= endLine {
+			break
+		}
+
+		pos = s.bMarks[nextLine] + s.tShift[nextLine]
+		max = s.eMarks[nextLine]
+
+		if pos >= max {
+			continue
+		}
+
+		if s.sCount[nextLine] < s.blkIndent {
+			break
+		}
+
+		if s.sCount[nextLine]-s.blkIndent > 3 {
+			continue
+		}
+
+		if src[pos] != ':' {
+			// Lines in fenced code do not close the container.
+			if ruleFence(s, nextLine, endLine, true) {
+				end, closed := fenceEnd(s, nextLine, endLine)
+				nextLine = end
+				if !closed {
+					nextLine--
+				}
+			}
+			continue
+		}
+
+		count := isContainerCloser(s, nextLine)
+		if count == 0 {
+			if ruleContainer(s, nextLine, endLine, true) {
+				depth++
+			}
+			continue
+		}
+
+		if depth > 0 {
+			depth--
+			continue
+		}
+
+		if count < len {
+			continue
+		}
+
+		haveEndMarker = true
+
+		break
+	}
+
+	oldParentType := s.parentType
+	oldLineMax := s.lineMax
+	s.parentType = ptContainer
+	s.lineMax = nextLine
+
+	tok := &ContainerOpen{
+		Name:  name,
+		Title: title,
+		Map:   [2]int{startLine, 0},
+	}
+	s.pushOpeningToken(tok)
+
+	s.md.block.tokenize(s, startLine+1, nextLine)
+
+	s.pushClosingToken(&ContainerClose{Name: name})
+
+	s.parentType = oldParentType
+	s.lineMax = oldLineMax
+
+	s.line = nextLine
+	if haveEndMarker {
+		s.line++
+	}
+	tok.Map[1] = s.line
+
+	return true
+}
@@ -0,0 +1,58 @@
+package markdown
+
+import (
+	"io"
+	"testing"
+)
+
+func TestParseContainerParams(t *testing.T) {
+	type testCase struct {
+		in    string
+		name  string
+		title string
+	}
+	testCases := []testCase{
+		{"", "", ""},
+		{"warning", "warning", ""},
+		{"warning Be careful", "warning", "Be careful"},
+		{"note   spaced  out ", "note", "spaced  out"},
+		{"x-y_z1", "x-y_z1", ""},
+		{"<b>", "", ""},
+		{"no!", "", ""},
+	}
+	for _, tc := range testCases {
+		name, title := parseContainerParams(tc.in)
+		if name != tc.name || title != tc.title {
+			t.Errorf("parseContainerParams(%q) = %q, %q, want %q, %q", tc.in, name, title, tc.name, tc.title)
+		}
+	}
+}
+
+func TestContainer(t *testing.T) {
+	details := func(w io.Writer, tok Token) {
+		if tok, ok := tok.(*ContainerOpen); ok {
+			io.WriteString(w, "<details><summary>"+tok.Title+"</summary>")
+			return
+		}
+		io.WriteString(w, "</details>")
+	}
+
+	runRenderTests(t, []renderTest{
+		{"::: warning\n*here be dragons*\n:::", "<div class=\"warning\">\n<p><em>here be dragons</em></p>\n</div>\n"},
+		{"::: tip Title\ntext\n:::", "<div class=\"tip\">\n<p class=\"tip-title\">Title</p>\n<p>text</p>\n</div>\n"},
+		{"::: a\n::: b\nx\n:::\n:::", "<div class=\"a\">\n<div class=\"b\">\n<p>x</p>\n</div>\n</div>\n"},
+		{":::: a\n::: b\nx\n:::\ny\n::::", "<div class=\"a\">\n<div class=\"b\">\n<p>x</p>\n</div>\n<p>y</p>\n</div>\n"},
+		{"text\n::: a\nx", "<p>text</p>\n<div class=\"a\">\n<p>x</p>\n</div>\n"},
+		{"::: spoiler Open me\nx\n:::", "<details><summary>Open me</summary>\n<p>x</p>\n</details>\n"},
+		{":::\nx\n:::", "<p>:::\nx\n:::</p>\n"},
+		{"::: warning\nx", "<div class=\"warning\">\n<p>x</p>\n</div>\n"},
+		{"  ::: warning\n  x\n  :::", "<div class=\"warning\">\n<p>x</p>\n</div>\n"},
+		{"    ::: warning\nx", "<pre><code>::: warning\n</code></pre>\n<p>x</p>\n"},
+		{"> ::: a\n> x\n> :::\nafter", "<blockquote>\n<div class=\"a\">\n<p>x</p>\n</div>\n</blockquote>\n<p>after</p>\n"},
+		{"::: <script>\nx\n:::", "<p>::: &lt;script&gt;\nx\n:::</p>\n"},
+		{"::: a\n```\n:::\n```\n:::\n", "<div class=\"a\">\n<pre><code>:::\n</code></pre>\n</div>\n"},
+		{"::: a\n~~~~\n:::\n~~~\n:::\n~~~~\n:::\nafter", "<div class=\"a\">\n<pre><code>:::\n~~~\n:::\n</code></pre>\n</div>\n<p>after</p>\n"},
+		{"::: a\n```\n:::\n", "<div class=\"a\">\n<pre><code>:::\n</code></pre>\n</div>\n"},
+		{"- ::: a\n  ```\n  :::\nb", "<ul>\n<li>\n<div class=\"a\">\n<pre><code>:::\n</code></pre>\n</div>\n</li>\n</ul>\n<p>b</p>\n"},
+	}, Containers(true), RenderContainer("spoiler", details))
+}
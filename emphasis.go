@@ -14,6 +14,7 @@
 package markdown
 
 import (
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -35,11 +36,17 @@ func scanDelims(s *stateInline, start int) (canOpen bool, canClose bool, count i
 
 	isLastSpaceOrStart := lastLen == 0 || unicode.IsSpace(lastChar)
 	isNextSpaceOrEnd := nextLen == 0 || unicode.IsSpace(nextChar)
-	isLastPunct := !isLastSpaceOrStart && (isMarkdownPunct(lastChar) || unicode.IsPunct(lastChar))
-	isNextPunct := !isNextSpaceOrEnd && (isMarkdownPunct(nextChar) || unicode.IsPunct(nextChar))
+	isLastPunct := !isLastSpaceOrStart && isPunct(lastChar)
+	isNextPunct := !isNextSpaceOrEnd && isPunct(nextChar)
 
-	leftFlanking := !isNextSpaceOrEnd && (!isNextPunct || isLastSpaceOrStart || isLastPunct)
-	rightFlanking := !isLastSpaceOrStart && (!isLastPunct || isNextSpaceOrEnd || isNextPunct)
+	// In the CJK-friendly mode a CJK character next to the run counts the
+	// same as whitespace or punctuation, as the words are not separated
+	// by spaces in these scripts.
+	isLastCJK := s.md.CJKFriendly && !isLastSpaceOrStart && isCJKRune(lastChar)
+	isNextCJK := s.md.CJKFriendly && !isNextSpaceOrEnd && isCJKRune(nextChar)
+
+	leftFlanking := !isNextSpaceOrEnd && (!isNextPunct || isLastSpaceOrStart || isLastPunct || isLastCJK)
+	rightFlanking := !isLastSpaceOrStart && (!isLastPunct || isNextSpaceOrEnd || isNextPunct || isNextCJK)
 
 	if marker == '_' {
 		canOpen = leftFlanking && (!rightFlanking || isLastPunct)
@@ -58,9 +65,42 @@ func init() {
 	em['*'], em['_'] = true, true
 }
 
+// delimiter is a run of * or _ that may open or close emphasis. The run
+// is pushed as a Text token, which processEmphasis turns into emphasis
+// tokens once the runs of its scope are paired.
+type delimiter struct {
+	token      int  // index of the Text token of the run
+	marker     byte // * or _
+	length     int  // length of the run, for the rule of 3
+	count      int  // characters of the run not paired yet
+	open       bool // whether the run can open emphasis
+	close      bool // whether the run can close emphasis
+	prev, next int  // neighbours in the delimiter stack
+
+	opens  []*emphasis // emphasis opened by the run, innermost first
+	closes []*emphasis // emphasis closed by the run, innermost first
+}
+
+// emphasis is a pair of delimiters.
+type emphasis struct {
+	marker byte
+	strong bool
+	text   bool // nested too deeply, written as text
+}
+
+func (e *emphasis) markup() string {
+	markup := "**"
+	if e.marker == '_' {
+		markup = "__"
+	}
+	if !e.strong {
+		return markup[:1]
+	}
+	return markup
+}
+
 func ruleEmphasis(s *stateInline, silent bool) (_ bool) {
 	src := s.src
-	max := s.posMax
 	start := s.pos
 	marker := src[start]
 
@@ -72,90 +112,201 @@ func ruleEmphasis(s *stateInline, silent bool) (_ bool) {
 		return
 	}
 
-	canOpen, _, startCount := scanDelims(s, start)
-	s.pos += startCount
-	if !canOpen {
+	canOpen, canClose, count := scanDelims(s, start)
+	s.pos += count
+	if !canOpen && !canClose {
 		s.pending.WriteString(src[start:s.pos])
 		return true
 	}
 
-	stack := []int{startCount}
-	found := false
-
-	for s.pos < max {
-		if src[s.pos] == marker {
-			canOpen, canClose, count := scanDelims(s, s.pos)
+	s.pushToken(s.env.arena.text(Text{Content: src[start:s.pos]}))
+	s.delimiters = append(s.delimiters, delimiter{
+		token:  len(s.tokens) - 1,
+		marker: marker,
+		length: count,
+		count:  count,
+		open:   canOpen,
+		close:  canClose,
+	})
 
-			if canClose {
-				oldCount := stack[len(stack)-1]
-				stack = stack[:len(stack)-1]
-				newCount := count
-
-				for oldCount != newCount {
-					if newCount < oldCount {
-						stack = append(stack, oldCount-newCount)
-						break
-					}
+	return true
+}
 
-					newCount -= oldCount
+// processEmphasis pairs the delimiters from bottom on, as in the "process
+// emphasis" procedure of the spec, and replaces them in the tokens from
+// tokStart on, which begin at the given level, with emphasis tokens.
+func (s *stateInline) processEmphasis(bottom, tokStart, level int) {
+	d := s.delimiters[bottom:]
+	if len(d) == 0 {
+		return
+	}
+	for i := range d {
+		d[i].prev, d[i].next = i-1, i+1
+	}
+	unlink := func(i int) {
+		prev, next := d[i].prev, d[i].next
+		if prev >= 0 {
+			d[prev].next = next
+		}
+		if next < len(d) {
+			d[next].prev = prev
+		}
+	}
 
-					if len(stack) == 0 {
-						break
-					}
+	// The lowest delimiter that may still open the emphasis closed by
+	// a run of each marker, length modulo 3 and ability to open.
+	var openersBottom [2][2][3]int
 
-					s.pos += oldCount
-					oldCount = stack[len(stack)-1]
-					stack = stack[:len(stack)-1]
-				}
+	for ci := 0; ci < len(d); {
+		c := &d[ci]
+		if !c.close || c.count == 0 {
+			ci++
+			continue
+		}
 
-				if len(stack) == 0 {
-					startCount = oldCount
-					found = true
-					break
-				}
+		markerIdx, openIdx := 0, 0
+		if c.marker == '_' {
+			markerIdx = 1
+		}
+		if c.open {
+			openIdx = 1
+		}
+		lowest := &openersBottom[markerIdx][openIdx][c.length%3]
 
-				s.pos += count
+		oi := c.prev
+		for ; oi >= *lowest && oi >= 0; oi = d[oi].prev {
+			s.env.steps++
+			o := &d[oi]
+			if o.marker != c.marker || !o.open || o.count == 0 {
 				continue
 			}
-
-			if canOpen {
-				stack = append(stack, count)
+			// The rule of 3.
+			if (o.close || c.open) && (o.length+c.length)%3 == 0 && (o.length%3 != 0 || c.length%3 != 0) {
+				continue
 			}
+			break
+		}
 
-			s.pos += count
+		if oi < *lowest || oi < 0 {
+			*lowest = ci
+			if !c.open {
+				unlink(ci)
+			}
+			ci++
 			continue
 		}
 
-		s.md.inline.skipToken(s)
-	}
-
-	if !found {
-		s.pos = start
-		return
+		o := &d[oi]
+		e := &emphasis{marker: c.marker, strong: o.count > 1 && c.count > 1}
+		use := 1
+		if e.strong {
+			use = 2
+		}
+		o.count -= use
+		c.count -= use
+		o.opens = append(o.opens, e)
+		c.closes = append(c.closes, e)
+
+		// The delimiters in between become text.
+		o.next = ci
+		c.prev = oi
+		if o.count == 0 {
+			unlink(oi)
+		}
+		if c.count == 0 {
+			unlink(ci)
+			ci++
+		}
 	}
 
-	s.posMax = s.pos
-	s.pos = start + startCount
+	s.replaceDelimiters(d, tokStart, level)
+	s.delimiters = s.delimiters[:bottom]
+}
 
-	count := startCount
-	for ; count > 1; count -= 2 {
-		s.pushOpeningToken(&StrongOpen{})
+// replaceDelimiters replaces the Text tokens of the delimiters d in the
+// tokens from tokStart on with the emphasis tokens and the unpaired
+// characters, joins the adjacent Text tokens and sets the levels.
+func (s *stateInline) replaceDelimiters(d []delimiter, tokStart, level int) {
+	tokens := s.tokens[tokStart:]
+	out := make([]Token, 0, len(tokens)+len(d))
+	lvl := level
+
+	var text *Text
+	var buf strings.Builder
+	addText := func(t *Text) {
+		if text == nil {
+			t.Lvl = lvl
+			text = t
+			out = append(out, t)
+			return
+		}
+		if buf.Len() == 0 {
+			buf.WriteString(text.Content)
+		}
+		buf.WriteString(t.Content)
 	}
-	if count > 0 {
-		s.pushOpeningToken(&EmphasisOpen{})
+	endText := func() {
+		if text != nil && buf.Len() > 0 {
+			text.Content = buf.String()
+			buf.Reset()
+		}
+		text = nil
+	}
+	add := func(tok Token) {
+		endText()
+		if tok.Closing() {
+			lvl--
+		}
+		tok.SetLevel(lvl)
+		if tok.Opening() {
+			lvl++
+		}
+		out = append(out, tok)
 	}
 
-	s.md.inline.tokenize(s)
+	di := 0
+	for i, tok := range tokens {
+		if di < len(d) && d[di].token == tokStart+i {
+			x := &d[di]
+			di++
+			for _, e := range x.closes {
+				switch {
+				case e.text:
+					addText(s.env.arena.text(Text{Content: e.markup()}))
+				case e.strong:
+					add(&StrongClose{})
+				default:
+					add(&EmphasisClose{})
+				}
+			}
+			if x.count > 0 {
+				t := tok.(*Text)
+				t.Content = t.Content[:x.count]
+				addText(t)
+			}
+			for j := len(x.opens) - 1; j >= 0; j-- {
+				e := x.opens[j]
+				switch {
+				case lvl >= s.md.MaxNesting:
+					s.env.nestingExceeded(s.md.MaxNesting)
+					e.text = true
+					addText(s.env.arena.text(Text{Content: e.markup()}))
+				case e.strong:
+					add(&StrongOpen{})
+				default:
+					add(&EmphasisOpen{})
+				}
+			}
+			continue
+		}
 
-	if count%2 != 0 {
-		s.pushClosingToken(&EmphasisClose{})
-	}
-	for count = startCount; count > 1; count -= 2 {
-		s.pushClosingToken(&StrongClose{})
+		if t, ok := tok.(*Text); ok {
+			addText(t)
+		} else {
+			add(tok)
+		}
 	}
+	endText()
 
-	s.pos = s.posMax + startCount
-	s.posMax = max
-
-	return true
+	s.tokens = append(s.tokens[:tokStart], out...)
 }
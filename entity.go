@@ -13,7 +13,10 @@
 
 package markdown
 
-import "github.com/opennota/html"
+import (
+	"github.com/opennota/byteutil"
+	"github.com/opennota/html"
+)
 
 func ruleEntity(s *stateInline, silent bool) (_ bool) {
 	pos := s.pos
@@ -26,7 +29,7 @@ func ruleEntity(s *stateInline, silent bool) (_ bool) {
 	max := s.posMax
 
 	if pos+1 < max {
-		if e, n := html.ParseEntity(src[pos:]); n > 0 {
+		if e, n := parseEntity(src[pos:]); n > 0 {
 			if !silent {
 				s.pending.WriteString(e)
 			}
@@ -42,3 +45,27 @@ func ruleEntity(s *stateInline, silent bool) (_ bool) {
 
 	return true
 }
+
+// parseEntity is html.ParseEntity restricted to the numeric references
+// allowed by CommonMark: &# followed by 1-7 decimal digits or by x and
+// 1-6 hexadecimal digits, and a semicolon.
+func parseEntity(s string) (string, int) {
+	if len(s) > 1 && s[1] == '#' {
+		i := 2
+		maxDigits := 7
+		isDigit := byteutil.IsDigit
+		if i < len(s) && (s[i] == 'x' || s[i] == 'X') {
+			i++
+			maxDigits = 6
+			isDigit = func(b byte) bool { return isHexDigit(byteutil.ByteToLower(b)) }
+		}
+		start := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == start || i-start > maxDigits || i >= len(s) || s[i] != ';' {
+			return "", 0
+		}
+	}
+	return html.ParseEntity(s)
+}
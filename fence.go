@@ -22,12 +22,12 @@ func init() {
 }
 
 func ruleFence(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
-	shift := s.tShift[startLine]
+	shift := s.sCount[startLine]
 	if shift < 0 {
 		return
 	}
 
-	pos := s.bMarks[startLine] + shift
+	pos := s.bMarks[startLine] + s.tShift[startLine]
 	max := s.eMarks[startLine]
 	src := s.src
 
@@ -50,7 +50,7 @@ func ruleFence(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 
 	params := strings.TrimSpace(src[pos:max])
 
-	if strings.IndexByte(params, '`') >= 0 {
+	if marker == '`' && strings.IndexByte(params, '`') >= 0 {
 		return
 	}
 
@@ -58,32 +58,58 @@ func ruleFence(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 		return true
 	}
 
-	nextLine := startLine
-	haveEndMarker := false
+	nextLine, haveEndMarker := fenceEnd(s, startLine, endLine)
 
+	s.line = nextLine
+	if haveEndMarker {
+		s.line++
+	}
+
+	lang, attrs := parseFenceInfo(params)
+	s.pushToken(&Fence{
+		Params:  params,
+		Lang:    lang,
+		Attrs:   attrs,
+		Content: s.lines(startLine+1, nextLine, s.sCount[startLine], true),
+		Map:     [2]int{startLine, nextLine},
+	})
+
+	return true
+}
+
+// fenceEnd returns the line that closes the fence opened at startLine and
+// whether there is one; if not, the fence goes on until endLine or until a
+// line indented less than the block.
+func fenceEnd(s *stateBlock, startLine, endLine int) (nextLine int, closed bool) {
+	src := s.src
+	mem := s.bMarks[startLine] + s.tShift[startLine]
+	marker := src[mem]
+	len := s.skipBytes(mem, marker) - mem
+
+	nextLine = startLine
 	for {
 		nextLine++
 		if nextLine >= endLine {
-			break
+			return nextLine, false
 		}
 
 		mem = s.bMarks[nextLine] + s.tShift[nextLine]
-		pos = mem
-		max = s.eMarks[nextLine]
+		pos := mem
+		max := s.eMarks[nextLine]
 
 		if pos >= max {
 			continue
 		}
 
-		if s.tShift[nextLine] < s.blkIndent {
-			break
+		if s.sCount[nextLine] < s.blkIndent {
+			return nextLine, false
 		}
 
 		if src[pos] != marker {
 			continue
 		}
 
-		if s.tShift[nextLine]-s.blkIndent > 3 {
+		if s.sCount[nextLine]-s.blkIndent > 3 {
 			continue
 		}
 
@@ -98,21 +124,6 @@ func ruleFence(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 			continue
 		}
 
-		haveEndMarker = true
-
-		break
-	}
-
-	s.line = nextLine
-	if haveEndMarker {
-		s.line++
+		return nextLine, true
 	}
-
-	s.pushToken(&Fence{
-		Params:  params,
-		Content: s.lines(startLine+1, nextLine, s.tShift[startLine], true),
-		Map:     [2]int{startLine, nextLine},
-	})
-
-	return true
 }
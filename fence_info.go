@@ -0,0 +1,302 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// LineRange is an inclusive range of 1-based line numbers.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// FenceAttrs holds the key=value attributes of a fence info string such as
+// ```go {linenos=true hl_lines=[3,5-7] title="main.go"}. Values are of type
+// bool (true/false, and keys without a value inside braces), int, string
+// (quoted or bare words) or []LineRange (bracketed lists of numbers and
+// ranges).
+type FenceAttrs map[string]interface{}
+
+func (a FenceAttrs) Has(key string) bool {
+	_, ok := a[key]
+	return ok
+}
+
+// Bool reports whether the attribute is set to a true-ish value.
+func (a FenceAttrs) Bool(key string) bool {
+	switch v := a[key].(type) {
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case string:
+		return v != "" && v != "false" && v != "no" && v != "off"
+	case []LineRange:
+		return len(v) > 0
+	}
+	return false
+}
+
+func (a FenceAttrs) Int(key string) (int, bool) {
+	switch v := a[key].(type) {
+	case int:
+		return v, true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func (a FenceAttrs) String(key string) string {
+	switch v := a[key].(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// Ranges returns the line ranges of an attribute written either as a list
+// ([3,5-7]) or as a string ("3 5-7").
+func (a FenceAttrs) Ranges(key string) []LineRange {
+	switch v := a[key].(type) {
+	case []LineRange:
+		return v
+	case int:
+		return []LineRange{{v, v}}
+	case string:
+		ranges, _ := parseLineRanges(v)
+		return ranges
+	}
+	return nil
+}
+
+func inLineRanges(ranges []LineRange, n int) bool {
+	for _, r := range ranges {
+		if n >= r.Start && n <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+func parseLineRanges(s string) (ranges []LineRange, ok bool) {
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		item = strings.Trim(item, `"'`)
+		var r LineRange
+		var err error
+		if i := strings.IndexByte(item, '-'); i > 0 {
+			if r.Start, err = strconv.Atoi(item[:i]); err != nil {
+				return nil, false
+			}
+			if r.End, err = strconv.Atoi(item[i+1:]); err != nil {
+				return nil, false
+			}
+		} else {
+			if r.Start, err = strconv.Atoi(item); err != nil {
+				return nil, false
+			}
+			r.End = r.Start
+		}
+		if r.Start < 1 || r.End < r.Start {
+			return nil, false
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, len(ranges) > 0
+}
+
+func isFenceAttrSep(b byte) bool {
+	return b == ' ' || b == '\t' || b == ','
+}
+
+func parseFenceValue(s string, pos int) (v interface{}, end int) {
+	switch s[pos] {
+	case '"', '\'':
+		quote := s[pos]
+		var buf []byte
+		for pos++; pos < len(s) && s[pos] != quote; pos++ {
+			if s[pos] == '\\' && pos+1 < len(s) {
+				pos++
+			}
+			buf = append(buf, s[pos])
+		}
+		if pos < len(s) {
+			pos++
+		}
+		return string(buf), pos
+
+	case '[':
+		start := pos
+		for pos < len(s) && s[pos] != ']' {
+			pos++
+		}
+		inner := s[start+1 : pos]
+		if pos < len(s) {
+			pos++
+		}
+		if ranges, ok := parseLineRanges(inner); ok {
+			return ranges, pos
+		}
+		return inner, pos
+	}
+
+	start := pos
+	for pos < len(s) && !isFenceAttrSep(s[pos]) {
+		pos++
+	}
+	word := s[start:pos]
+	switch word {
+	case "true":
+		return true, pos
+	case "false":
+		return false, pos
+	}
+	if n, err := strconv.Atoi(word); err == nil {
+		return n, pos
+	}
+	return word, pos
+}
+
+// parseFenceAttrs parses the attributes in s. Keys without a value are
+// true if bare is set, and are skipped otherwise, so that the words of a
+// plain info string are not taken for attributes.
+func parseFenceAttrs(s string, bare bool) FenceAttrs {
+	var attrs FenceAttrs
+	pos := 0
+	for pos < len(s) {
+		for pos < len(s) && isFenceAttrSep(s[pos]) {
+			pos++
+		}
+		start := pos
+		for pos < len(s) && !isFenceAttrSep(s[pos]) && s[pos] != '=' {
+			pos++
+		}
+		if pos == start {
+			if pos < len(s) {
+				pos++
+			}
+			continue
+		}
+		key := s[start:pos]
+
+		var v interface{} = true
+		if pos+1 < len(s) && s[pos] == '=' {
+			v, pos = parseFenceValue(s, pos+1)
+		} else if !bare {
+			continue
+		}
+
+		if attrs == nil {
+			attrs = make(FenceAttrs)
+		}
+		attrs[key] = v
+	}
+	return attrs
+}
+
+// parseFenceInfo splits the fence info string into the language (its
+// first word) and the attributes that follow it: any attributes in braces,
+// or else only the explicit key=value pairs.
+func parseFenceInfo(info string) (lang string, attrs FenceAttrs) {
+	if info == "" {
+		return
+	}
+
+	if info[0] != '{' {
+		rest := ""
+		if i := strings.IndexByte(info, ' '); i >= 0 {
+			info, rest = info[:i], strings.TrimSpace(info[i+1:])
+		}
+		lang = unescapeAll(info)
+		info = rest
+	}
+
+	if len(info) >= 2 && info[0] == '{' && info[len(info)-1] == '}' {
+		return lang, parseFenceAttrs(info[1:len(info)-1], true)
+	}
+
+	return lang, parseFenceAttrs(info, false)
+}
+
+func htmlTagName(tag string) string {
+	i := 1
+	for i < len(tag) && tag[i] != ' ' && tag[i] != '>' && tag[i] != '/' {
+		i++
+	}
+	return tag[1:i]
+}
+
+// splitHTMLLines splits the HTML of a code block into lines, closing the
+// elements left open at the end of a line and reopening them at the
+// beginning of the next one. A trailing newline does not start a new line.
+func splitHTMLLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+
+	var lines []string
+	var open []string
+	var buf bytes.Buffer
+	pos := 0
+	for pos < len(s) {
+		switch s[pos] {
+		case '<':
+			end := strings.IndexByte(s[pos:], '>')
+			if end < 0 {
+				buf.WriteString(s[pos:])
+				pos = len(s)
+				continue
+			}
+			tag := s[pos : pos+end+1]
+			if strings.HasPrefix(tag, "</") {
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				}
+			} else if !strings.HasSuffix(tag, "/>") {
+				open = append(open, tag)
+			}
+			buf.WriteString(tag)
+			pos += end + 1
+
+		case '\n':
+			for i := len(open) - 1; i >= 0; i-- {
+				buf.WriteString("</")
+				buf.WriteString(htmlTagName(open[i]))
+				buf.WriteByte('>')
+			}
+			lines = append(lines, buf.String())
+			buf.Reset()
+			for _, tag := range open {
+				buf.WriteString(tag)
+			}
+			pos++
+
+		default:
+			buf.WriteByte(s[pos])
+			pos++
+		}
+	}
+
+	return append(lines, buf.String())
+}
@@ -0,0 +1,93 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFenceInfo(t *testing.T) {
+	type testCase struct {
+		in    string
+		lang  string
+		attrs FenceAttrs
+	}
+	testCases := []testCase{
+		{"", "", nil},
+		{"go", "go", nil},
+		{`c\+\+ extra`, "c++", nil},
+		{"js Example title here", "js", nil},
+		{"py Example title=here", "py", FenceAttrs{"title": "here"}},
+		{"go {linenos}", "go", FenceAttrs{"linenos": true}},
+		{`go {linenos=true hl_lines=[3,5-7] title="main.go"}`, "go", FenceAttrs{
+			"linenos":  true,
+			"hl_lines": []LineRange{{3, 3}, {5, 7}},
+			"title":    "main.go",
+		}},
+		{`{linenostart=10, title='a \' b', hl_lines=[x]}`, "", FenceAttrs{
+			"linenostart": 10,
+			"title":       "a ' b",
+			"hl_lines":    "x",
+		}},
+		{`sh hl_lines="2 4-5" wrap=false`, "sh", FenceAttrs{
+			"hl_lines": "2 4-5",
+			"wrap":     false,
+		}},
+	}
+	for _, tc := range testCases {
+		lang, attrs := parseFenceInfo(tc.in)
+		if lang != tc.lang || !reflect.DeepEqual(attrs, tc.attrs) {
+			t.Errorf("parseFenceInfo(%q) = %q, %#v, want %q, %#v", tc.in, lang, attrs, tc.lang, tc.attrs)
+		}
+	}
+}
+
+func TestParseLineRanges(t *testing.T) {
+	type testCase struct {
+		in   string
+		want []LineRange
+	}
+	testCases := []testCase{
+		{"", nil},
+		{"1", []LineRange{{1, 1}}},
+		{`3, "5-7" 9`, []LineRange{{3, 3}, {5, 7}, {9, 9}}},
+		{"0", nil},
+		{"7-5", nil},
+		{"a-b", nil},
+	}
+	for _, tc := range testCases {
+		got, _ := parseLineRanges(tc.in)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseLineRanges(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSplitHTMLLines(t *testing.T) {
+	type testCase struct {
+		in   string
+		want []string
+	}
+	testCases := []testCase{
+		{"", nil},
+		{"a\n", []string{"a"}},
+		{"a\n\nb", []string{"a", "", "b"}},
+		{"<i>x</i>\n<span class=\"c\">/* a\nb */</span>\n",
+			[]string{"<i>x</i>", "<span class=\"c\">/* a</span>", "<span class=\"c\">b */</span>"}},
+	}
+	for _, tc := range testCases {
+		got := splitHTMLLines(tc.in)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitHTMLLines(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFenceAttrs(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"```go title=\"main.go\"\nx\n```", "<figure class=\"code-block\"><figcaption>main.go</figcaption><pre><code class=\"language-go\">x\n</code></pre></figure>\n"},
+		{"```js Example title here\nx\n```", "<pre><code class=\"language-js\">x\n</code></pre>\n"},
+		{"```go title=true\nx\n```", "<pre><code class=\"language-go\">x\n</code></pre>\n"},
+		{"```go title=2\nx\n```", "<pre><code class=\"language-go\">x\n</code></pre>\n"},
+		{"```go {linenos=true hl_lines=[2] linenostart=9}\na\n<b>\n```", "<pre><code class=\"language-go\"><span class=\"line\"><span class=\"ln\">9</span>a</span>\n<span class=\"line hl\"><span class=\"ln\">10</span>&lt;b&gt;</span>\n</code></pre>\n"},
+	})
+}
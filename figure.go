@@ -0,0 +1,73 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "strconv"
+
+// figureImage returns the image of a paragraph that consists of a single
+// image with a title, or nil.
+func figureImage(tok *Inline) *Image {
+	if len(tok.Children) != 1 {
+		return nil
+	}
+	img, ok := tok.Children[0].(*Image)
+	if !ok || img.Title == "" {
+		return nil
+	}
+	return img
+}
+
+func ruleFigures(s *stateCore) {
+	if !s.md.Figures {
+		return
+	}
+
+	label := s.md.FigureLabel
+	tokens := s.tokens
+	for i := 0; i+2 < len(tokens); i++ {
+		open, ok := tokens[i].(*ParagraphOpen)
+		if !ok || open.Tight {
+			continue
+		}
+		inline, ok := tokens[i+1].(*Inline)
+		if !ok {
+			continue
+		}
+		img := figureImage(inline)
+		if img == nil {
+			continue
+		}
+
+		figOpen := &FigureOpen{
+			Label:   label,
+			Caption: img.Title,
+			Map:     open.Map,
+			Lvl:     open.Lvl,
+		}
+		if label != "" {
+			s.env.figures++
+			figOpen.Number = s.env.figures
+			figOpen.ID = "fig-" + strconv.Itoa(s.env.figures)
+		}
+
+		tokens[i] = figOpen
+		tokens[i+2] = &FigureClose{
+			Number:  figOpen.Number,
+			Label:   label,
+			Caption: img.Title,
+			Lvl:     open.Lvl,
+		}
+		i += 2
+	}
+}
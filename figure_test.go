@@ -0,0 +1,27 @@
+package markdown
+
+import "testing"
+
+func TestFigures(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{`![alt](a.png "Caption")`, "<figure><img src=\"a.png\" alt=\"alt\" title=\"Caption\"><figcaption>Caption</figcaption></figure>\n"},
+		{`![alt](a.png)`, "<p><img src=\"a.png\" alt=\"alt\"></p>\n"},
+		{`See ![alt](a.png "Caption")`, "<p>See <img src=\"a.png\" alt=\"alt\" title=\"Caption\"></p>\n"},
+		{"- ![alt](a.png \"Caption\")", "<ul>\n<li><img src=\"a.png\" alt=\"alt\" title=\"Caption\"></li>\n</ul>\n"},
+
+		// Only an image alone in its paragraph makes a figure.
+		{"![a](a.png \"A\") ![b](b.png \"B\")", "<p><img src=\"a.png\" alt=\"a\" title=\"A\"> <img src=\"b.png\" alt=\"b\" title=\"B\"></p>\n"},
+		{"[![a](a.png \"A\")](/x)", "<p><a href=\"/x\"><img src=\"a.png\" alt=\"a\" title=\"A\"></a></p>\n"},
+		{"> ![a](a.png \"A\")", "<blockquote>\n<figure><img src=\"a.png\" alt=\"a\" title=\"A\"><figcaption>A</figcaption></figure>\n</blockquote>\n"},
+		{"![a][r]\n\n[r]: a.png \"Ref <title>\"", "<figure><img src=\"a.png\" alt=\"a\" title=\"Ref &lt;title&gt;\"><figcaption>Ref &lt;title&gt;</figcaption></figure>\n"},
+	}, Figures(true))
+
+	runRenderTests(t, []renderTest{
+		{"![a](a.png \"One\")\n\n![b](b.png \"Two &amp; three\")", "<figure id=\"fig-1\"><img src=\"a.png\" alt=\"a\" title=\"One\"><figcaption>Figure 1: One</figcaption></figure>\n<figure id=\"fig-2\"><img src=\"b.png\" alt=\"b\" title=\"Two &amp; three\"><figcaption>Figure 2: Two &amp; three</figcaption></figure>\n"},
+		{"![a](a.png)\n\n![b](b.png \"B\")", "<p><img src=\"a.png\" alt=\"a\"></p>\n<figure id=\"fig-1\"><img src=\"b.png\" alt=\"b\" title=\"B\"><figcaption>Figure 1: B</figcaption></figure>\n"},
+	}, Figures(true), NumberFigures("Figure"))
+
+	runRenderTests(t, []renderTest{
+		{`![alt](a.png "Caption")`, "<p><img src=\"a.png\" alt=\"alt\" title=\"Caption\"></p>\n"},
+	})
+}
@@ -0,0 +1,303 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/opennota/byteutil"
+)
+
+// autoLink is a link found in plain text.
+type autoLink struct {
+	start int
+	end   int
+	href  string
+}
+
+func isAlnum(b byte) bool {
+	return byteutil.IsLetter(b) || byteutil.IsDigit(b)
+}
+
+// gfmAutolinkBoundary reports whether an extended autolink may start at
+// pos: at the beginning of the text, after whitespace or after one of the
+// delimiters *, _, ~ and (.
+func gfmAutolinkBoundary(s string, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	switch s[pos-1] {
+	case '*', '_', '~', '(':
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:pos])
+	return unicode.IsSpace(r)
+}
+
+// scanGFMDomain returns the end of the valid domain at pos: segments of
+// alphanumerics, underscores and hyphens separated by periods, with at
+// least one period and no underscores in the last two segments.
+func scanGFMDomain(s string, pos int) int {
+	start := pos
+	periods := 0
+	lastUnderscore, prevUnderscore := false, false
+loop:
+	for pos < len(s) {
+		b := s[pos]
+		switch {
+		case b == '.':
+			if pos == start || s[pos-1] == '.' || pos+1 >= len(s) || !(isAlnum(s[pos+1]) || s[pos+1] == '_' || s[pos+1] == '-') {
+				break loop
+			}
+			periods++
+			prevUnderscore, lastUnderscore = lastUnderscore, false
+		case b == '_':
+			lastUnderscore = true
+		case b == '-' || isAlnum(b) || b >= 0x80:
+		default:
+			break loop
+		}
+		pos++
+	}
+
+	if periods == 0 || lastUnderscore || prevUnderscore {
+		return 0
+	}
+	return pos
+}
+
+// trimGFMAutolink removes the trailing punctuation, unbalanced closing
+// parentheses and entity references from the end of an autolink.
+func trimGFMAutolink(s string, start, end int) int {
+	for end > start {
+		switch s[end-1] {
+		case '?', '!', '.', ',', ':', '*', '_', '~', '\'', '"':
+			end--
+			continue
+		case ')':
+			if strings.Count(s[start:end], ")") > strings.Count(s[start:end], "(") {
+				end--
+				continue
+			}
+		case ';':
+			i := end - 2
+			for i > start && isAlnum(s[i]) {
+				i--
+			}
+			if i > start && s[i] == '&' && i < end-2 {
+				end = i
+				continue
+			}
+		}
+		break
+	}
+	return end
+}
+
+func scanGFMPath(s string, pos int) int {
+	for pos < len(s) && s[pos] != '<' {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+func matchGFMURL(s string, pos int) (end int, href string) {
+	rest := s[pos:]
+	var domainStart int
+	switch {
+	case strings.HasPrefix(rest, "www."):
+		domainStart = pos
+		href = "http://"
+	case strings.HasPrefix(rest, "http://"):
+		domainStart = pos + len("http://")
+	case strings.HasPrefix(rest, "https://"):
+		domainStart = pos + len("https://")
+	case strings.HasPrefix(rest, "ftp://"):
+		domainStart = pos + len("ftp://")
+	default:
+		return 0, ""
+	}
+
+	end = scanGFMDomain(s, domainStart)
+	if end == 0 {
+		return 0, ""
+	}
+	end = trimGFMAutolink(s, pos, scanGFMPath(s, end))
+	return end, href + s[pos:end]
+}
+
+func isEmailLocalByte(b byte) bool {
+	return isAlnum(b) || b == '.' || b == '-' || b == '_' || b == '+'
+}
+
+func matchGFMEmail(s string, pos int) (end int, href string) {
+	at := pos
+	for at < len(s) && isEmailLocalByte(s[at]) {
+		at++
+	}
+	if at == pos || at >= len(s) || s[at] != '@' {
+		return 0, ""
+	}
+
+	end = at + 1
+	periods := 0
+	for end < len(s) {
+		b := s[end]
+		if b == '.' && end+1 < len(s) && isAlnum(s[end+1]) && end > at+1 {
+			periods++
+		} else if !(isAlnum(b) || b == '-' || b == '_') {
+			break
+		}
+		end++
+	}
+	if periods == 0 || s[end-1] == '-' || s[end-1] == '_' {
+		return 0, ""
+	}
+	return end, "mailto:" + s[pos:end]
+}
+
+// findGFMAutolinks finds the extended www, http(s), ftp and email autolinks of
+// the GFM spec.
+func findGFMAutolinks(s string) (links []autoLink) {
+	for pos := 0; pos < len(s); pos++ {
+		b := s[pos]
+		if !isAlnum(b) || !gfmAutolinkBoundary(s, pos) {
+			continue
+		}
+
+		end, href := matchGFMURL(s, pos)
+		if end == 0 {
+			end, href = matchGFMEmail(s, pos)
+		}
+		if end == 0 {
+			continue
+		}
+
+		links = append(links, autoLink{pos, end, href})
+		pos = end - 1
+	}
+	return
+}
+
+var taskMarkers = map[string]bool{
+	"[ ]": false,
+	"[x]": true,
+	"[X]": true,
+}
+
+// ruleTaskLists turns the list items that start with [ ] or [x] into
+// task list items with a checkbox.
+func ruleTaskLists(s *stateCore) {
+	if !s.md.GFM {
+		return
+	}
+
+	tokens := s.tokens
+	for i := 0; i+2 < len(tokens); i++ {
+		if _, ok := tokens[i].(*ListItemOpen); !ok {
+			continue
+		}
+		if _, ok := tokens[i+1].(*ParagraphOpen); !ok {
+			continue
+		}
+		inline, ok := tokens[i+2].(*Inline)
+		if !ok || len(inline.Content) < 4 || len(inline.Children) == 0 {
+			continue
+		}
+		checked, ok := taskMarkers[inline.Content[:3]]
+		if !ok || inline.Content[3] != ' ' && inline.Content[3] != '\t' {
+			continue
+		}
+		text, ok := inline.Children[0].(*Text)
+		if !ok || len(text.Content) < 4 || text.Content[:3] != inline.Content[:3] {
+			continue
+		}
+
+		text.Content = text.Content[3:]
+		s.env.countToken()
+		inline.Children = append([]Token{&TaskCheckbox{
+			Checked: checked,
+			Lvl:     text.Lvl,
+		}}, inline.Children...)
+	}
+}
+
+var filteredTags = []string{
+	"title", "textarea", "style", "xmp", "iframe",
+	"noembed", "noframes", "script", "plaintext",
+}
+
+// filterTags escapes the opening < of the tags that GFM disallows in raw
+// HTML.
+func filterTags(s string) string {
+	if strings.IndexByte(s, '<') < 0 {
+		return s
+	}
+
+	var buf strings.Builder
+	last := 0
+	for pos := 0; pos < len(s); pos++ {
+		if s[pos] != '<' {
+			continue
+		}
+		name := pos + 1
+		if name < len(s) && s[name] == '/' {
+			name++
+		}
+		for _, tag := range filteredTags {
+			end := name + len(tag)
+			if end > len(s) || !strings.EqualFold(s[name:end], tag) {
+				continue
+			}
+			if end < len(s) && s[end] != '>' && s[end] != '/' && s[end] != ' ' && s[end] != '\t' && s[end] != '\n' {
+				continue
+			}
+			buf.WriteString(s[last:pos])
+			buf.WriteString("&lt;")
+			last = pos + 1
+			break
+		}
+	}
+
+	if last == 0 {
+		return s
+	}
+	buf.WriteString(s[last:])
+	return buf.String()
+}
+
+func ruleTagFilter(s *stateCore) {
+	if !s.md.GFM || !s.md.HTML {
+		return
+	}
+
+	for _, tok := range s.tokens {
+		switch tok := tok.(type) {
+		case *HTMLBlock:
+			tok.Content = filterTags(tok.Content)
+		case *Inline:
+			for _, itok := range tok.Children {
+				if itok, ok := itok.(*HTMLInline); ok {
+					itok.Content = filterTags(itok.Content)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "strings"
+
+type gridRow struct {
+	lines [][]rune
+	start int
+	end   int
+}
+
+// parseGridBorder returns the column positions of the '+' signs of a
+// border line such as +---+:==:+ and the alignments of its columns.
+func parseGridBorder(line []rune) (cols []int, aligns []Align, header bool) {
+	if len(line) < 3 || line[0] != '+' || line[len(line)-1] != '+' {
+		return nil, nil, false
+	}
+
+	fill := rune(0)
+	start := 0
+	for i := 1; i < len(line); i++ {
+		switch r := line[i]; r {
+		case '+':
+			if i-start < 2 {
+				return nil, nil, false
+			}
+			left := line[start+1] == ':'
+			right := line[i-1] == ':'
+			switch {
+			case left && right:
+				aligns = append(aligns, AlignCenter)
+			case left:
+				aligns = append(aligns, AlignLeft)
+			case right:
+				aligns = append(aligns, AlignRight)
+			default:
+				aligns = append(aligns, AlignNone)
+			}
+			cols = append(cols, start)
+			start = i
+		case '-', '=':
+			if fill == 0 {
+				fill = r
+			} else if fill != r {
+				return nil, nil, false
+			}
+		case ':':
+			if line[i-1] != '+' && line[i+1] != '+' {
+				return nil, nil, false
+			}
+		default:
+			return nil, nil, false
+		}
+	}
+	if fill == 0 {
+		return nil, nil, false
+	}
+
+	return append(cols, start), aligns, fill == '='
+}
+
+func isGridRowLine(line []rune, cols []int) bool {
+	if len(line) != cols[len(cols)-1]+1 {
+		return false
+	}
+	for _, c := range cols {
+		if line[c] != '|' {
+			return false
+		}
+	}
+	return true
+}
+
+func sameGridColumns(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// gridCellContent cuts the text of the column between the borders at
+// from and to out of the row lines, removing the common indentation.
+func gridCellContent(lines [][]rune, from, to int) string {
+	texts := make([]string, len(lines))
+	indent := -1
+	for i, line := range lines {
+		text := strings.TrimRight(string(line[from+1:to]), " \t")
+		texts[i] = text
+		if text == "" {
+			continue
+		}
+		n := len(text) - len(strings.TrimLeft(text, " "))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	var buf strings.Builder
+	for _, text := range texts {
+		if len(text) > indent && indent >= 0 {
+			buf.WriteString(text[indent:])
+		}
+		buf.WriteByte('\n')
+	}
+	return strings.Trim(buf.String(), "\n")
+}
+
+// pushGridCell tokenizes the content of a cell with the block parser. A
+// cell holding a single paragraph is rendered without the <p> element.
+func pushGridCell(s *stateBlock, content string) {
+	ns := newStateBlock([]byte(content), s.md, s.env)
+	ns.level = s.level
+	s.md.block.tokenize(ns, 0, ns.lineMax)
+
+	tokens := ns.tokens
+	if len(tokens) == 3 {
+		if tok, ok := tokens[0].(*ParagraphOpen); ok {
+			tok.Tight = true
+			tokens[2].(*ParagraphClose).Tight = true
+		}
+	}
+
+	s.tokens = append(s.tokens, tokens...)
+}
+
+func ruleGridTable(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
+	if !s.md.GridTables {
+		return
+	}
+
+	shift := s.sCount[startLine]
+	if shift < 0 || shift-s.blkIndent > 3 {
+		return
+	}
+
+	pos := s.bMarks[startLine] + s.tShift[startLine]
+	max := s.eMarks[startLine]
+	if pos+1 >= max || s.src[pos] != '+' || s.src[pos+1] != '-' && s.src[pos+1] != ':' {
+		return
+	}
+
+	cols, aligns, header := parseGridBorder([]rune(strings.TrimSpace(s.src[pos:max])))
+	if cols == nil || header {
+		return
+	}
+
+	var rows []gridRow
+	var lines [][]rune
+	headerRows := 0
+	nextLine := startLine + 1
+	closed := false
+	for ; nextLine < endLine; nextLine++ {
+		shift := s.sCount[nextLine]
+		if shift < 0 || shift-s.blkIndent > 3 {
+			break
+		}
+		line := []rune(strings.TrimSpace(s.src[s.bMarks[nextLine]+s.tShift[nextLine] : s.eMarks[nextLine]]))
+		if len(line) == 0 || line[0] != '+' && line[0] != '|' {
+			break
+		}
+
+		if line[0] == '|' {
+			if !isGridRowLine(line, cols) {
+				return
+			}
+			lines = append(lines, line)
+			closed = false
+			continue
+		}
+
+		c, a, h := parseGridBorder(line)
+		if !sameGridColumns(c, cols) || len(lines) == 0 {
+			return
+		}
+		if h {
+			if headerRows > 0 || len(rows) > 0 {
+				return
+			}
+			headerRows = 1
+			for _, align := range a {
+				if align != AlignNone {
+					aligns = a
+					break
+				}
+			}
+		}
+		rows = append(rows, gridRow{
+			lines: lines,
+			start: nextLine - len(lines),
+			end:   nextLine,
+		})
+		lines = nil
+		closed = true
+	}
+
+	if !closed {
+		return
+	}
+
+	if silent {
+		return true
+	}
+
+	s.pushOpeningToken(&TableOpen{
+		Map: [2]int{startLine, nextLine},
+	})
+
+	for i, row := range rows {
+		if i == 0 && headerRows > 0 {
+			s.pushOpeningToken(&TheadOpen{
+				Map: [2]int{startLine, row.end + 1},
+			})
+		} else if i == headerRows {
+			s.pushOpeningToken(&TbodyOpen{
+				Map: [2]int{row.start - 1, nextLine},
+			})
+		}
+
+		s.pushOpeningToken(&TrOpen{
+			Map: [2]int{row.start, row.end},
+		})
+		for j := 0; j < len(aligns); j++ {
+			content := gridCellContent(row.lines, cols[j], cols[j+1])
+			if i < headerRows {
+				s.pushOpeningToken(&ThOpen{
+					Align: aligns[j],
+					Map:   [2]int{row.start, row.end},
+				})
+				pushGridCell(s, content)
+				s.pushClosingToken(&ThClose{})
+			} else {
+				s.pushOpeningToken(&TdOpen{
+					Align: aligns[j],
+					Map:   [2]int{row.start, row.end},
+				})
+				pushGridCell(s, content)
+				s.pushClosingToken(&TdClose{})
+			}
+		}
+		s.pushClosingToken(&TrClose{})
+
+		if i < headerRows {
+			s.pushClosingToken(&TheadClose{})
+		}
+	}
+
+	if len(rows) > headerRows {
+		s.pushClosingToken(&TbodyClose{})
+	}
+
+	s.pushClosingToken(&TableClose{})
+
+	s.line = nextLine
+
+	return true
+}
@@ -0,0 +1,16 @@
+package markdown
+
+import "testing"
+
+func TestGridTable(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"+---+---+\n| a | b |\n+===+===+\n| 1 | 2 |\n+---+---+", "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"+-------+--------+\n| - x   | ```    |\n| - y   | code   |\n|       | ```    |\n+-------+--------+", "<table>\n<tbody>\n<tr>\n<td>\n<ul>\n<li>x</li>\n<li>y</li>\n</ul>\n</td>\n<td>\n<pre><code>code\n</code></pre>\n</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"+:--+--:+\n| a | b |\n+---+---+", "<table>\n<tbody>\n<tr>\n<td style=\"text-align:left\">a</td>\n<td style=\"text-align:right\">b</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"+---+---+\n| a | b |\n| c |\n+---+---+", "<p>+---+---+\n| a | b |\n| c |\n+---+---+</p>\n"},
+		{"+---+---+\n| a | b |", "<p>+---+---+\n| a | b |</p>\n"},
+		{"+---+\n| a |\n+---+\n| b |\n+---+", "<table>\n<tbody>\n<tr>\n<td>a</td>\n</tr>\n<tr>\n<td>b</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"+---+\n| *a* |\n+---+", "<p>+---+\n| <em>a</em> |\n+---+</p>\n"},
+		{"text\n+---+\n| a |\n+---+", "<p>text\n+---+\n| a |\n+---+</p>\n"},
+	}, GridTables(true), Typographer(false))
+}
@@ -0,0 +1,113 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "strings"
+
+// In the hardened mode the inline rules give up on the openers that would
+// otherwise make them scan the rest of the text again and again:
+//
+//   - a strikethrough opener with no potential closer after it, or with
+//     more than MaxNesting unclosed openers between it and its closer;
+//   - a link label with no ] after it, or with more than MaxNesting
+//     nested brackets;
+//   - an autolink, inline HTML or <...> link destination with no > after
+//     it;
+//   - a code span whose backtick run is known not to occur again.
+//
+// This keeps the inline parsing roughly linear in the size of the input.
+
+// scanCache holds what the hardened mode learned about the source of an
+// inline state.
+type scanCache struct {
+	lastBracket int      // position of the last ], or -1
+	lastGT      int      // position of the last >, or -1
+	lastCloser  [256]int // position of the last ~ that may close, or -1
+
+	// The last position of each length of backtick runs seen by a failed
+	// code span scan from backtickPos to backtickMax.
+	backticks   map[int]int
+	backtickPos int
+	backtickMax int
+}
+
+func isASCIISpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\v'
+}
+
+func newScanCache(src string) *scanCache {
+	c := &scanCache{
+		lastBracket: strings.LastIndexByte(src, ']'),
+		lastGT:      strings.LastIndexByte(src, '>'),
+		backtickPos: -1,
+	}
+	c.lastCloser['~'] = -1
+	for i := len(src) - 1; i > 0; i-- {
+		if src[i] == '~' && !isASCIISpace(src[i-1]) {
+			c.lastCloser['~'] = i
+			break
+		}
+	}
+	return c
+}
+
+// hardened returns the scan cache of s, or nil unless the hardened mode
+// is on.
+func (s *stateInline) hardened() *scanCache {
+	if !s.md.Hardened {
+		return nil
+	}
+	if s.scan == nil {
+		s.scan = newScanCache(s.src)
+	}
+	return s.scan
+}
+
+// noCloserAfter reports whether no run of marker at or after end can close
+// the run that ends there.
+func (c *scanCache) noCloserAfter(marker byte, end int) bool {
+	return c.lastCloser[marker] < end
+}
+
+// tooDeep reports whether the hardened mode gives up on a delimiter or
+// bracket stack of the given depth.
+func (s *stateInline) tooDeep(depth int) bool {
+	return s.md.Hardened && depth > s.md.MaxNesting
+}
+
+// scanLinkDestination parses the destination of an inline link or image
+// at pos. In the hardened mode, a <...> destination with no > after it
+// fails without the scan. A failed scan counts as one to the end.
+func (s *stateInline) scanLinkDestination(pos int) (url string, endpos int, ok bool) {
+	if c := s.hardened(); c != nil && s.src[pos] == '<' && c.lastGT <= pos {
+		return
+	}
+	url, endpos, ok = parseLinkDestination(s.src, pos, s.posMax)
+	if ok {
+		s.env.steps += endpos - pos
+	} else {
+		s.env.steps += s.posMax - pos
+	}
+	return
+}
+
+// noBacktickRun reports whether a code span opened at pos with a run of
+// the given length is known not to be closed before max.
+func (c *scanCache) noBacktickRun(length, pos, max int) bool {
+	if c.backtickPos < 0 || c.backtickMax != max || pos < c.backtickPos {
+		return false
+	}
+	last, ok := c.backticks[length]
+	return !ok || last <= pos
+}
@@ -16,12 +16,12 @@ package markdown
 import "strings"
 
 func ruleHeading(s *stateBlock, startLine, _ int, silent bool) (_ bool) {
-	shift := s.tShift[startLine]
+	shift := s.sCount[startLine]
 	if shift < 0 {
 		return
 	}
 
-	pos := s.bMarks[startLine] + shift
+	pos := s.bMarks[startLine] + s.tShift[startLine]
 	max := s.eMarks[startLine]
 	src := s.src
 
@@ -37,7 +37,7 @@ func ruleHeading(s *stateBlock, startLine, _ int, silent bool) (_ bool) {
 		pos++
 	}
 
-	if level > 6 || (pos < max && src[pos] != ' ') {
+	if level > 6 || (pos < max && src[pos] != ' ' && src[pos] != '\t') {
 		return
 	}
 
@@ -45,9 +45,9 @@ func ruleHeading(s *stateBlock, startLine, _ int, silent bool) (_ bool) {
 		return true
 	}
 
-	max = s.skipBytesBack(max, ' ', pos)
+	max = s.skipSpacesBack(max, pos)
 	tmp := s.skipBytesBack(max, '#', pos)
-	if tmp > pos && src[tmp-1] == ' ' {
+	if tmp > pos && (src[tmp-1] == ' ' || src[tmp-1] == '\t') {
 		max = tmp
 	}
 
@@ -19,6 +19,10 @@ func parseLinkLabel(s *stateInline, start int, disableNested bool) int {
 	max := s.posMax
 	oldPos := s.pos
 
+	if c := s.hardened(); c != nil && c.lastBracket <= start {
+		return -1
+	}
+
 	s.pos = start + 1
 	level := 1
 	found := false
@@ -41,6 +45,9 @@ func parseLinkLabel(s *stateInline, start int, disableNested bool) int {
 		if marker == '[' {
 			if prevPos == s.pos-1 {
 				level++
+				if s.tooDeep(level) {
+					break
+				}
 			} else if disableNested {
 				s.pos = oldPos
 				return -1
@@ -63,7 +70,7 @@ func parseLinkDestination(s string, pos, max int) (url string, endpos int, ok bo
 		pos++
 		for pos < max {
 			b := s[pos]
-			if b == '\n' {
+			if b == '\n' || b == '<' {
 				return
 			}
 			if b == '>' {
@@ -102,22 +109,22 @@ func parseLinkDestination(s string, pos, max int) (url string, endpos int, ok bo
 
 		if b == '(' {
 			level++
-			if level > 1 {
-				break
+			if level > 32 {
+				return
 			}
 		}
 
 		if b == ')' {
-			level--
-			if level < 0 {
+			if level == 0 {
 				break
 			}
+			level--
 		}
 
 		pos++
 	}
 
-	if start == pos {
+	if start == pos || level != 0 {
 		return
 	}
 
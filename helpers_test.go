@@ -46,7 +46,8 @@ func TestParseLinkDestination(t *testing.T) {
 		{"http://google.com", "http://google.com", 17, true},
 		{"http://google.com/search?query=(1)", "http://google.com/search?query=(1)", 34, true},
 		{"http://google.com/search?query=)1(", "http://google.com/search?query=", 31, true},
-		{"http://google.com/search?query=((1))", "http://google.com/search?query=(", 32, true},
+		{"http://google.com/search?query=((1))", "http://google.com/search?query=((1))", 36, true},
+		{"http://google.com/search?query=((1)", "", 0, false},
 		{`http://google.com/search?query=a\ b\ c`, `http://google.com/search?query=a\ b\ c`, 38, true},
 		{"http://goo\x00gle.com", "http://goo", 10, true},
 		{"<link>", "link", 6, true},
@@ -57,6 +58,7 @@ func TestParseLinkDestination(t *testing.T) {
 		{"<>", "", 2, true},
 		{"<link", "", 0, false},
 		{"<\n", "", 0, false},
+		{"<a<b>", "", 0, false},
 	}
 	for _, tc := range testCases {
 		title, endpos, ok := parseLinkDestination(tc.in, 0, len(tc.in))
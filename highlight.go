@@ -0,0 +1,348 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/opennota/byteutil"
+	"github.com/opennota/html"
+)
+
+// Highlighter converts the content of fenced code blocks into HTML.
+//
+// Highlight returns the HTML for code written in lang (the first word of
+// the info string, possibly empty), or ok = false to let the renderer
+// escape the code as usual. The result is written inside
+// <pre><code class="language-lang"> unless it starts with "<pre", in which
+// case it replaces the whole block. The result is not escaped or
+// sanitized by the renderer.
+type Highlighter interface {
+	Highlight(lang, code string) (html string, ok bool)
+}
+
+// lexFunc returns the CSS class (without the prefix) and the end of the
+// token starting at pos; an empty class means plain text.
+type lexFunc func(code string, pos int) (class string, end int)
+
+type builtinHighlighter struct {
+	prefix string
+}
+
+// NewHighlighter returns a dependency-free Highlighter for Go, shell, JSON,
+// YAML and diff. Tokens are wrapped into <span class="prefix+kind">, where
+// kind is one of kw, str, com, num, lit, var, key, add, del, hunk and meta.
+func NewHighlighter(classPrefix string) Highlighter {
+	return builtinHighlighter{classPrefix}
+}
+
+var lexers = map[string]lexFunc{
+	"go":      lexGo,
+	"golang":  lexGo,
+	"sh":      lexShell,
+	"bash":    lexShell,
+	"shell":   lexShell,
+	"zsh":     lexShell,
+	"console": lexShell,
+	"json":    lexJSON,
+	"yaml":    lexYAML,
+	"yml":     lexYAML,
+	"diff":    lexDiff,
+	"patch":   lexDiff,
+}
+
+func (h builtinHighlighter) Highlight(lang, code string) (string, bool) {
+	lex, ok := lexers[strings.ToLower(lang)]
+	if !ok {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	plain := 0
+	pos := 0
+	for pos < len(code) {
+		class, end := lex(code, pos)
+		if end <= pos {
+			end = pos + 1
+		}
+		if class == "" {
+			pos = end
+			continue
+		}
+
+		textEscaper.WriteString(&buf, code[plain:pos])
+		buf.WriteString(`<span class="`)
+		html.WriteEscapedString(&buf, h.prefix+class)
+		buf.WriteString(`">`)
+		textEscaper.WriteString(&buf, code[pos:end])
+		buf.WriteString("</span>")
+		pos = end
+		plain = end
+	}
+	textEscaper.WriteString(&buf, code[plain:])
+
+	return buf.String(), true
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || byteutil.IsLetter(b) || byteutil.IsDigit(b)
+}
+
+func skipIdent(code string, pos int) int {
+	for pos < len(code) && isIdentByte(code[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func skipToEOL(code string, pos int) int {
+	if i := strings.IndexByte(code[pos:], '\n'); i >= 0 {
+		return pos + i
+	}
+	return len(code)
+}
+
+func skipQuoted(code string, pos int, quote byte, escapes, multiline bool) int {
+	pos++
+	for pos < len(code) {
+		switch b := code[pos]; {
+		case b == quote:
+			return pos + 1
+		case b == '\\' && escapes:
+			pos++
+		case b == '\n' && !multiline:
+			return pos
+		}
+		pos++
+	}
+	return len(code)
+}
+
+func isLineStart(code string, pos int) bool {
+	return pos == 0 || code[pos-1] == '\n'
+}
+
+func wordSet(words string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	goKeywords = wordSet(`break case chan const continue default defer else
+		fallthrough for func go goto if import interface map package range
+		return select struct switch type var`)
+	goLiterals = wordSet(`true false nil iota`)
+
+	shellKeywords = wordSet(`if then else elif fi for while until do done
+		case esac in function select time return export local readonly`)
+
+	yamlLiterals = wordSet(`true false null yes no on off True False Null
+		Yes No On Off TRUE FALSE NULL YES NO ON OFF ~`)
+)
+
+func lexNumber(code string, pos int) int {
+	for pos < len(code) && (isIdentByte(code[pos]) || code[pos] == '.') {
+		pos++
+	}
+	return pos
+}
+
+func lexGo(code string, pos int) (string, int) {
+	b := code[pos]
+	switch {
+	case strings.HasPrefix(code[pos:], "//"):
+		return "com", skipToEOL(code, pos)
+	case strings.HasPrefix(code[pos:], "/*"):
+		if i := strings.Index(code[pos+2:], "*/"); i >= 0 {
+			return "com", pos + i + 4
+		}
+		return "com", len(code)
+	case b == '"':
+		return "str", skipQuoted(code, pos, '"', true, false)
+	case b == '\'':
+		return "str", skipQuoted(code, pos, '\'', true, false)
+	case b == '`':
+		return "str", skipQuoted(code, pos, '`', false, true)
+	case byteutil.IsDigit(b):
+		return "num", lexNumber(code, pos)
+	case isIdentByte(b):
+		end := skipIdent(code, pos)
+		word := code[pos:end]
+		if goKeywords[word] {
+			return "kw", end
+		}
+		if goLiterals[word] {
+			return "lit", end
+		}
+		return "", end
+	}
+	return "", pos + 1
+}
+
+func isShellWordByte(b byte) bool {
+	return isIdentByte(b) || b == '-' || b == '.' || b == '/' || b == ':' || b == '=' || b >= 0x80
+}
+
+func lexShell(code string, pos int) (string, int) {
+	b := code[pos]
+	switch {
+	case b == '#' && (pos == 0 || code[pos-1] == ' ' || code[pos-1] == '\t' || code[pos-1] == '\n'):
+		return "com", skipToEOL(code, pos)
+	case b == '\'':
+		return "str", skipQuoted(code, pos, '\'', false, true)
+	case b == '"':
+		return "str", skipQuoted(code, pos, '"', true, true)
+	case b == '$' && pos+1 < len(code):
+		next := code[pos+1]
+		if next == '{' {
+			if i := strings.IndexByte(code[pos:], '}'); i >= 0 {
+				return "var", pos + i + 1
+			}
+			return "", pos + 1
+		}
+		if isIdentByte(next) {
+			return "var", skipIdent(code, pos+1)
+		}
+		if strings.IndexByte("@*#?$!-", next) >= 0 {
+			return "var", pos + 2
+		}
+	case isShellWordByte(b):
+		end := pos
+		for end < len(code) && isShellWordByte(code[end]) {
+			end++
+		}
+		if shellKeywords[code[pos:end]] {
+			return "kw", end
+		}
+		return "", end
+	}
+	return "", pos + 1
+}
+
+func skipSpacesAndTabs(code string, pos int) int {
+	for pos < len(code) && (code[pos] == ' ' || code[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+func lexJSON(code string, pos int) (string, int) {
+	b := code[pos]
+	switch {
+	case b == '"':
+		end := skipQuoted(code, pos, '"', true, false)
+		if next := skipSpacesAndTabs(code, end); next < len(code) && code[next] == ':' {
+			return "key", end
+		}
+		return "str", end
+	case b == '-' || byteutil.IsDigit(b):
+		end := pos + 1
+		for end < len(code) && (byteutil.IsDigit(code[end]) || strings.IndexByte(".eE+-", code[end]) >= 0) {
+			end++
+		}
+		return "num", end
+	case byteutil.IsLetter(b):
+		end := skipIdent(code, pos)
+		switch code[pos:end] {
+		case "true", "false", "null":
+			return "lit", end
+		}
+		return "", end
+	}
+	return "", pos + 1
+}
+
+// isYAMLKeyPosition reports whether only indentation and sequence dashes
+// precede pos on its line.
+func isYAMLKeyPosition(code string, pos int) bool {
+	for i := pos - 1; i >= 0 && code[i] != '\n'; i-- {
+		b := code[i]
+		if b != ' ' && b != '\t' && !(b == '-' && i+1 < len(code) && code[i+1] == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
+func lexYAML(code string, pos int) (string, int) {
+	b := code[pos]
+	switch {
+	case b == '#' && (pos == 0 || code[pos-1] == ' ' || code[pos-1] == '\t' || code[pos-1] == '\n'):
+		return "com", skipToEOL(code, pos)
+	case isLineStart(code, pos) && (strings.HasPrefix(code[pos:], "---") || strings.HasPrefix(code[pos:], "...")):
+		return "meta", pos + 3
+	case b == '"':
+		return "str", skipQuoted(code, pos, '"', true, false)
+	case b == '\'':
+		return "str", skipQuoted(code, pos, '\'', false, false)
+	case b == ' ' || b == '\t' || b == '\n' || b == '-':
+		return "", pos + 1
+	}
+
+	eol := skipToEOL(code, pos)
+	if isYAMLKeyPosition(code, pos) {
+		for i := pos; i < eol; i++ {
+			if code[i] == '#' {
+				break
+			}
+			if code[i] == ':' && (i+1 == eol || code[i+1] == ' ') {
+				return "key", i
+			}
+		}
+	}
+
+	end := pos
+	for end < eol && code[end] != ' ' && code[end] != ',' && code[end] != ']' && code[end] != '}' {
+		end++
+	}
+	if end == pos {
+		return "", pos + 1
+	}
+	word := code[pos:end]
+	if yamlLiterals[word] {
+		return "lit", end
+	}
+	if byteutil.IsDigit(word[0]) || (len(word) > 1 && (word[0] == '-' || word[0] == '.') && byteutil.IsDigit(word[1])) {
+		return "num", end
+	}
+	return "", end
+}
+
+func lexDiff(code string, pos int) (string, int) {
+	eol := skipToEOL(code, pos)
+	if eol == pos {
+		return "", pos + 1
+	}
+	if !isLineStart(code, pos) {
+		return "", eol
+	}
+
+	line := code[pos:eol]
+	switch {
+	case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "),
+		strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+		return "meta", eol
+	case strings.HasPrefix(line, "@@"):
+		return "hunk", eol
+	case line[0] == '+':
+		return "add", eol
+	case line[0] == '-':
+		return "del", eol
+	}
+	return "", eol
+}
@@ -0,0 +1,62 @@
+package markdown
+
+import "testing"
+
+func TestBuiltinHighlighter(t *testing.T) {
+	type testCase struct {
+		lang string
+		in   string
+		want string
+	}
+	testCases := []testCase{
+		{"go", `if x := "a<b"; x != nil { return 42 } // done`,
+			`<span class="hl-kw">if</span> x := <span class="hl-str">"a&lt;b"</span>; x != <span class="hl-lit">nil</span> { <span class="hl-kw">return</span> <span class="hl-num">42</span> } <span class="hl-com">// done</span>`},
+		{"Go", "/* a\nb */x", "<span class=\"hl-com\">/* a\nb */</span>x"},
+		{"sh", `for f in *.md; do echo "$f" ${HOME} $1; done # loop`,
+			`<span class="hl-kw">for</span> f <span class="hl-kw">in</span> *.md; <span class="hl-kw">do</span> echo <span class="hl-str">"$f"</span> <span class="hl-var">${HOME}</span> <span class="hl-var">$1</span>; <span class="hl-kw">done</span> <span class="hl-com"># loop</span>`},
+		{"bash", "git checkout --done a#b", "git checkout --done a#b"},
+		{"json", `{"a": [1.5e3, true, "x"]}`,
+			`{<span class="hl-key">"a"</span>: [<span class="hl-num">1.5e3</span>, <span class="hl-lit">true</span>, <span class="hl-str">"x"</span>]}`},
+		{"yaml", "---\nname: x # c\n- on: 3\n",
+			"<span class=\"hl-meta\">---</span>\n<span class=\"hl-key\">name</span>: x <span class=\"hl-com\"># c</span>\n- <span class=\"hl-key\">on</span>: <span class=\"hl-num\">3</span>\n"},
+		{"diff", "--- a\n+++ b\n@@ -1 +1 @@\n-old\n+new\n ctx",
+			"<span class=\"hl-meta\">--- a</span>\n<span class=\"hl-meta\">+++ b</span>\n<span class=\"hl-hunk\">@@ -1 +1 @@</span>\n<span class=\"hl-del\">-old</span>\n<span class=\"hl-add\">+new</span>\n ctx"},
+	}
+	h := NewHighlighter("hl-")
+	for _, tc := range testCases {
+		got, ok := h.Highlight(tc.lang, tc.in)
+		if !ok {
+			t.Errorf("Highlight(%q, %q): not supported", tc.lang, tc.in)
+		} else if got != tc.want {
+			t.Errorf("Highlight(%q, %q):\n got %q\nwant %q", tc.lang, tc.in, got, tc.want)
+		}
+	}
+
+	if _, ok := h.Highlight("cobol", "x"); ok {
+		t.Error("Highlight(\"cobol\", ...) should not be supported")
+	}
+}
+
+type preHighlighter struct{}
+
+func (preHighlighter) Highlight(lang, code string) (string, bool) {
+	if lang != "raw" {
+		return "", false
+	}
+	return "<pre class=\"raw\">" + code + "</pre>", true
+}
+
+func TestHighlight(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"```go\nvar x\n```", "<pre><code class=\"language-go\"><span class=\"kw\">var</span> x\n</code></pre>\n"},
+		{"```\n<x>\n```", "<pre><code>&lt;x&gt;\n</code></pre>\n"},
+		{"```GO\nfunc\n```", "<pre><code class=\"language-GO\"><span class=\"kw\">func</span>\n</code></pre>\n"},
+		{"```go\n\"unterminated\n```", "<pre><code class=\"language-go\"><span class=\"str\">\"unterminated</span>\n</code></pre>\n"},
+		{"    var x\n", "<pre><code>var x\n</code></pre>\n"},
+	}, Highlight(NewHighlighter("")))
+
+	got, _ := render("```raw\n<b>\n```\n\n```c\n<b>\n```", Highlight(preHighlighter{}))
+	if want := "<pre class=\"raw\"><b>\n</pre>\n<pre><code class=\"language-c\">&lt;b&gt;\n</code></pre>\n"; got != want {
+		t.Errorf("render with a <pre> highlighter = %q, want %q", got, want)
+	}
+}
@@ -20,12 +20,12 @@ func init() {
 }
 
 func ruleHR(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
-	shift := s.tShift[startLine]
+	shift := s.sCount[startLine]
 	if shift < 0 {
 		return
 	}
 
-	pos := s.bMarks[startLine] + shift
+	pos := s.bMarks[startLine] + s.tShift[startLine]
 	src := s.src
 
 	marker := src[pos]
@@ -41,7 +41,7 @@ func ruleHR(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 	for pos < max {
 		c := src[pos]
 		pos++
-		if c != marker && c != ' ' {
+		if c != marker && c != ' ' && c != '\t' {
 			return
 		}
 		if c == marker {
@@ -13,174 +13,233 @@
 
 package markdown
 
-import "github.com/opennota/byteutil"
+import (
+	"strings"
+
+	"github.com/opennota/byteutil"
+)
 
 var (
 	htmlBlocks = []string{
+		"address",
 		"article",
 		"aside",
+		"base",
+		"basefont",
 		"blockquote",
 		"body",
-		"button",
-		"canvas",
 		"caption",
+		"center",
 		"col",
 		"colgroup",
 		"dd",
+		"details",
+		"dialog",
+		"dir",
 		"div",
 		"dl",
 		"dt",
-		"embed",
 		"fieldset",
 		"figcaption",
 		"figure",
 		"footer",
 		"form",
+		"frame",
+		"frameset",
 		"h1",
 		"h2",
 		"h3",
 		"h4",
 		"h5",
 		"h6",
+		"head",
 		"header",
-		"hgroup",
 		"hr",
+		"html",
 		"iframe",
+		"legend",
 		"li",
-		"map",
-		"object",
+		"link",
+		"main",
+		"menu",
+		"menuitem",
+		"nav",
+		"noframes",
 		"ol",
-		"output",
+		"optgroup",
+		"option",
 		"p",
-		"pre",
-		"progress",
-		"script",
+		"param",
+		"search",
 		"section",
-		"style",
+		"summary",
 		"table",
 		"tbody",
 		"td",
-		"textarea",
 		"tfoot",
 		"th",
 		"thead",
+		"title",
 		"tr",
+		"track",
 		"ul",
-		"video",
 	}
 
 	htmlBlocksSet = make(map[string]bool)
 
-	htmlSecond    [256]bool
-	piOrComment   [256]bool
-	slashOrLetter [256]bool
+	// htmlRawTags are the tags whose content is not markdown, up to the
+	// closing tag.
+	htmlRawTags = map[string]bool{
+		"pre":      true,
+		"script":   true,
+		"style":    true,
+		"textarea": true,
+	}
+
+	htmlSecond [256]bool
 )
 
 func init() {
 	for _, tag := range htmlBlocks {
 		htmlBlocksSet[tag] = true
 	}
-	for _, b := range "!/?abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
-		htmlSecond[b] = true
-	}
-	piOrComment['!'], piOrComment['?'] = true, true
-	for _, b := range "/abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
-		slashOrLetter[b] = true
-	}
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
 }
 
+// matchTagName returns the lowercased name of the tag at the start of s,
+// which follows the < of an open or closing tag, if the name is followed
+// by a space, >, /> or the end of s.
 func matchTagName(s string) string {
-	if len(s) < 2 {
-		return ""
-	}
-
 	i := 0
-	if s[0] == '/' {
+	if i < len(s) && s[i] == '/' {
 		i++
 	}
 	start := i
-	max := min(15+i, len(s))
-	for i < max && byteutil.IsLetter(s[i]) {
+	if i >= len(s) || !byteutil.IsLetter(s[i]) {
+		return ""
+	}
+	for i < len(s) && (byteutil.IsLetter(s[i]) || byteutil.IsDigit(s[i])) {
 		i++
 	}
-	if i >= len(s) {
-		return ""
+	if i < len(s) {
+		switch s[i] {
+		case ' ', '\n', '>':
+		case '/':
+			if !strings.HasPrefix(s[i:], "/>") {
+				return ""
+			}
+		default:
+			return ""
+		}
 	}
 
-	switch s[i] {
-	case ' ', '\n', '/', '>':
-		return byteutil.ToLower(s[start:i])
-	default:
-		return ""
-	}
+	return byteutil.ToLower(s[start:i])
 }
 
-func ruleHTMLBlock(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
-	if !s.md.HTML {
-		return
+// htmlBlockKind returns the kind of the HTML block that starts with line,
+// 1 to 7 as numbered in the CommonMark spec, or 0.
+func htmlBlockKind(line string) int {
+	if len(line) < 2 || line[0] != '<' {
+		return 0
 	}
 
-	shift := s.tShift[startLine]
-	if shift < 0 {
-		return
+	switch {
+	case strings.HasPrefix(line, "<!--"):
+		return 2
+	case line[1] == '?':
+		return 3
+	case strings.HasPrefix(line, "<![CDATA["):
+		return 5
+	case line[1] == '!' && len(line) > 2 && byteutil.IsLetter(line[2]):
+		return 4
 	}
 
-	pos := s.bMarks[startLine] + shift
-	max := s.eMarks[startLine]
-
-	if shift > 3 {
-		return
+	name := matchTagName(line[1:])
+	if htmlRawTags[name] {
+		if line[1] != '/' && !strings.HasPrefix(line[1+len(name):], "/") {
+			return 1
+		}
+		return 0
+	}
+	if htmlBlocksSet[name] {
+		return 6
 	}
 
-	if pos+2 >= max {
-		return
+	if line[1] != '/' && !byteutil.IsLetter(line[1]) {
+		return 0
+	}
+	n := matchHTMLTag(line)
+	if n == 0 || strings.TrimSpace(line[n:]) != "" {
+		return 0
 	}
+	return 7
+}
 
-	src := s.src
+// htmlBlockEnds reports whether line ends an HTML block of the given kind.
+func htmlBlockEnds(kind int, line string) bool {
+	switch kind {
+	case 1:
+		line = byteutil.ToLower(line)
+		for tag := range htmlRawTags {
+			if strings.Contains(line, "</"+tag+">") {
+				return true
+			}
+		}
+		return false
+	case 2:
+		return strings.Contains(line, "-->")
+	case 3:
+		return strings.Contains(line, "?>")
+	case 4:
+		return strings.Contains(line, ">")
+	case 5:
+		return strings.Contains(line, "]]>")
+	}
+	return strings.TrimSpace(line) == ""
+}
 
-	if src[pos] != '<' {
+func ruleHTMLBlock(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
+	if !s.md.HTML {
 		return
 	}
 
-	b := src[pos+1]
-	if !htmlSecond[b] {
+	shift := s.sCount[startLine]
+	if shift < 0 || shift-s.blkIndent > 3 {
 		return
 	}
 
-	if slashOrLetter[b] {
-		tag := matchTagName(src[pos+1 : max])
-		if tag == "" {
-			return
-		}
-		if !htmlBlocksSet[tag] {
-			return
-		}
-		if silent {
-			return true
-		}
-	} else if piOrComment[b] {
-		if silent {
-			return true
-		}
-	} else {
+	line := s.src[s.bMarks[startLine]+s.tShift[startLine] : s.eMarks[startLine]]
+	kind := htmlBlockKind(line)
+	if kind == 0 {
 		return
 	}
 
+	if silent {
+		// The last kind cannot interrupt a paragraph.
+		return kind != 7
+	}
+
 	nextLine := startLine + 1
-	for nextLine < s.lineMax && !s.isLineEmpty(nextLine) {
-		nextLine++
+	if !htmlBlockEnds(kind, line) {
+		for ; nextLine < endLine; nextLine++ {
+			shift := s.sCount[nextLine]
+			if shift < 0 || shift < s.blkIndent && !s.isLineEmpty(nextLine) {
+				break
+			}
+
+			line := s.src[s.bMarks[nextLine]+s.tShift[nextLine] : s.eMarks[nextLine]]
+			if htmlBlockEnds(kind, line) {
+				// A blank line ending the block is not a part of it.
+				if line != "" {
+					nextLine++
+				}
+				break
+			}
+		}
 	}
 
 	s.line = nextLine
 	s.pushToken(&HTMLBlock{
-		Content: s.lines(startLine, nextLine, 0, true),
+		Content: s.lines(startLine, nextLine, s.blkIndent, true),
 		Map:     [2]int{startLine, s.line},
 	})
 
@@ -9,15 +9,19 @@ func TestMatchTagName(t *testing.T) {
 	}
 	testCases := []testCase{
 		{"", ""},
-		{"a", ""},
+		{"a", "a"},
 		{"/a>", "a"},
 		{"a>", "a"},
 		{"A>", "a"},
 		{"a\n", "a"},
 		{"br/>", "br"},
-		{"em", ""},
-		{"waytoolongtobeatag>", ""},
-		{"waytoolongtobeatag", ""},
+		{"br/", ""},
+		{"em", "em"},
+		{"h1 class=x>", "h1"},
+		{"1a>", ""},
+		{"/", ""},
+		{"a-b>", ""},
+		{"waytoolongtobeatag>", "waytoolongtobeatag"},
 	}
 	for _, tc := range testCases {
 		got := matchTagName(tc.in)
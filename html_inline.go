@@ -28,6 +28,10 @@ func ruleHTMLInline(s *stateInline, silent bool) (_ bool) {
 		return
 	}
 
+	if c := s.hardened(); c != nil && c.lastGT <= pos {
+		return
+	}
+
 	match := matchHTML(src[pos:])
 	if match == "" {
 		return
@@ -8,8 +8,10 @@ func TestMatchHTML(t *testing.T) {
 		want string
 	}
 	testCases := []testCase{
-		{"<!-->", ""},
+		{"<!-->", "<!-->"},
+		{"<!--->", "<!--->"},
 		{"<!-- ->", ""},
+		{"<!-- a -- b -->", "<!-- a -- b -->"},
 		{"<!-- -- >", ""},
 		{"<!-- -*- -->", "<!-- -*- -->"},
 		{"<!#-- -->", ""},
@@ -50,8 +52,9 @@ func TestMatchHTML(t *testing.T) {
 		{"<![CDATA[...]#  >", ""},
 		{"<![CDATA[ xxx xxx xxx ]]>", "<![CDATA[ xxx xxx xxx ]]>"},
 		{"<!-- comment -->", "<!-- comment -->"},
-		{"<!doctype html>", ""},
-		{"<!Doctype html>", ""},
+		{"<!doctype html>", "<!doctype html>"},
+		{"<!Doctype html>", "<!Doctype html>"},
+		{"<!1doctype html>", ""},
 		{"<!DOCTYPE html>", "<!DOCTYPE html>"},
 		{"<em><b", "<em>"},
 		{"<em><b>", "<em><b>"},
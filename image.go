@@ -39,46 +39,46 @@ func ruleImage(s *stateInline, silent bool) (_ bool) {
 	var href, title, label string
 	oldPos := pos
 	pos = labelEnd + 1
+	parseReference := true
 	if pos < max && src[pos] == '(' {
+		parseReference = false
 		pos = skipws(src, pos+1, max)
 		if pos >= max {
 			return
 		}
 
-		url, endpos, ok := parseLinkDestination(src, pos, s.posMax)
+		url, endpos, ok := s.scanLinkDestination(pos)
 		if ok {
 			url = normalizeLink(url)
-			if validateLink(url) {
+			if s.md.allowsURL(url, URLImage) {
 				href = url
 				pos = endpos
 			}
-		}
-
-		start := pos
-		pos = skipws(src, pos, max)
-		if pos >= max {
-			return
-		}
 
-		title, _, endpos, ok = parseLinkTitle(src, pos, s.posMax)
-		if pos < max && start != pos && ok {
-			pos = skipws(src, endpos, max)
+			start := pos
+			pos = skipws(src, pos, max)
+			if pos < max && start != pos {
+				if t, _, endpos, ok := parseLinkTitle(src, pos, s.posMax); ok {
+					title = t
+					pos = skipws(src, endpos, max)
+				}
+			}
 		}
 
 		if pos >= max || src[pos] != ')' {
-			s.pos = oldPos
-			return
+			// Not an inline link; try a shortcut reference instead.
+			parseReference = true
+			href, title = "", ""
 		}
 
 		pos++
+	}
 
-	} else {
+	if parseReference {
 		if s.env.References == nil {
 			return
 		}
 
-		pos = skipws(src, pos, max)
-
 		if pos < max && src[pos] == '[' {
 			start := pos + 1
 			pos = parseLinkLabel(s, pos, false)
@@ -97,7 +97,7 @@ func ruleImage(s *stateInline, silent bool) (_ bool) {
 		}
 
 		ref, ok := s.env.References[normalizeReference(label)]
-		if !ok {
+		if !ok || !s.md.allowsURL(ref["href"], URLImage) {
 			s.pos = oldPos
 			return
 		}
@@ -120,7 +120,7 @@ func ruleImage(s *stateInline, silent bool) (_ bool) {
 		newState.md.inline.tokenize(&newState)
 
 		s.pushToken(&Image{
-			Src:    href,
+			Src:    s.md.rewriteURL(href, URLImage),
 			Title:  title,
 			Tokens: newState.tokens,
 		})
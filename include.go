@@ -0,0 +1,210 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const maxIncludeDepth = 8
+
+var (
+	// ErrIncludeCycle is the error of an IncludeError for a file that
+	// includes itself, directly or not.
+	ErrIncludeCycle = errors.New("include cycle")
+
+	// ErrIncludeDepth is the error of an IncludeError for a file nested
+	// more than 8 includes deep.
+	ErrIncludeDepth = fmt.Errorf("includes nested more than %d deep", maxIncludeDepth)
+)
+
+func (t *IncludeError) Error() string {
+	return "markdown: include " + t.Path + ": " + t.Err.Error()
+}
+
+func (t *IncludeError) Unwrap() error { return t.Err }
+
+// parseIncludeDirective parses the lines
+//
+//	!include part.md [shift=1] [lines=10-20]
+//	{{< include "part.md" [shift=1] [lines=10-20] >}}
+func parseIncludeDirective(line string) (name string, attrs FenceAttrs) {
+	switch {
+	case strings.HasPrefix(line, "!include "):
+		line = line[len("!include "):]
+	case strings.HasPrefix(line, "{{<") && strings.HasSuffix(line, ">}}"):
+		line = strings.TrimSpace(line[3 : len(line)-3])
+		if !strings.HasPrefix(line, "include ") {
+			return "", nil
+		}
+		line = line[len("include "):]
+	default:
+		return "", nil
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", nil
+	}
+
+	v, end := parseFenceValue(line, 0)
+	name, ok := v.(string)
+	if !ok {
+		return "", nil
+	}
+
+	return name, parseFenceAttrs(line[end:], true)
+}
+
+func resolveInclude(includes []string, name string) string {
+	if !strings.HasPrefix(name, "/") && len(includes) > 0 {
+		name = path.Join(path.Dir(includes[len(includes)-1]), name)
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !fs.ValidPath(name) {
+		return ""
+	}
+	return name
+}
+
+func shiftHeadings(tokens []Token, shift int) {
+	clamp := func(n int) int {
+		n += shift
+		if n < 1 {
+			return 1
+		}
+		if n > 6 {
+			return 6
+		}
+		return n
+	}
+
+	for _, tok := range tokens {
+		switch tok := tok.(type) {
+		case *HeadingOpen:
+			tok.HLevel = clamp(tok.HLevel)
+		case *HeadingClose:
+			tok.HLevel = clamp(tok.HLevel)
+		}
+	}
+}
+
+func ruleInclude(s *stateBlock, startLine, _ int, silent bool) (_ bool) {
+	fsys := s.md.IncludeFS
+	if fsys == nil {
+		return
+	}
+
+	shift := s.sCount[startLine]
+	if shift < 0 || shift-s.blkIndent > 3 {
+		return
+	}
+
+	pos := s.bMarks[startLine] + s.tShift[startLine]
+	max := s.eMarks[startLine]
+	src := s.src
+
+	if pos >= max || (src[pos] != '!' && src[pos] != '{') {
+		return
+	}
+
+	directive := strings.TrimSpace(src[pos:max])
+	name, attrs := parseIncludeDirective(directive)
+	if name == "" {
+		return
+	}
+
+	if silent {
+		return true
+	}
+
+	var err error
+	file := resolveInclude(s.env.includes, name)
+	switch {
+	case file == "":
+		err = fs.ErrInvalid
+	case len(s.env.includes) >= maxIncludeDepth:
+		err = ErrIncludeDepth
+	default:
+		for _, n := range s.env.includes {
+			if n == file {
+				err = ErrIncludeCycle
+				break
+			}
+		}
+	}
+	var data []byte
+	if err == nil {
+		data, err = fs.ReadFile(fsys, file)
+	}
+
+	if err != nil {
+		s.line = startLine + 1
+		if file == "" {
+			file = name
+		}
+		s.pushToken(&IncludeError{
+			Path:      file,
+			Directive: directive,
+			Err:       err,
+			Map:       [2]int{startLine, s.line},
+		})
+		return true
+	}
+	name = file
+
+	ns := newStateBlock(data, s.md, s.env)
+	ns.level = s.level
+	lineMax := ns.lineMax
+
+	s.env.includes = append(s.env.includes, name)
+
+	ranges := attrs.Ranges("lines")
+	if ranges == nil {
+		ranges = []LineRange{{1, lineMax}}
+	}
+	for _, r := range ranges {
+		begin, end := r.Start-1, r.End
+		if end > lineMax {
+			end = lineMax
+		}
+		if begin >= end {
+			continue
+		}
+		ns.line = begin
+		ns.lineMax = end
+		s.md.block.tokenize(ns, begin, end)
+	}
+
+	s.env.includes = s.env.includes[:len(s.env.includes)-1]
+
+	if n, ok := attrs.Int("shift"); ok {
+		shiftHeadings(ns.tokens, n)
+	}
+
+	s.line = startLine + 1
+
+	s.pushToken(&IncludeOpen{
+		Path: name,
+		Map:  [2]int{startLine, s.line},
+	})
+	s.tokens = append(s.tokens, ns.tokens...)
+	s.pushToken(&IncludeClose{Path: name})
+
+	return true
+}
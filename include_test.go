@@ -0,0 +1,120 @@
+package markdown
+
+import (
+	"errors"
+	"io/fs"
+	"strconv"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseIncludeDirective(t *testing.T) {
+	type testCase struct {
+		in    string
+		name  string
+		shift int
+	}
+	testCases := []testCase{
+		{"", "", 0},
+		{"!include", "", 0},
+		{"!include part.md", "part.md", 0},
+		{`!include "my part.md" shift=2`, "my part.md", 2},
+		{`{{< include "part.md" shift=-1 >}}`, "part.md", -1},
+		{`{{< figure "part.md" >}}`, "", 0},
+		{"!included part.md", "", 0},
+	}
+	for _, tc := range testCases {
+		name, attrs := parseIncludeDirective(tc.in)
+		shift, _ := attrs.Int("shift")
+		if name != tc.name || shift != tc.shift {
+			t.Errorf("parseIncludeDirective(%q) = %q, shift=%d, want %q, shift=%d", tc.in, name, shift, tc.name, tc.shift)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"intro.md":  {Data: []byte("# Intro\n\nHello [ref].\n")},
+		"ch/one.md": {Data: []byte("## One\n\n!include two.md\n")},
+		"ch/two.md": {Data: []byte("Two\n")},
+		"loop.md":   {Data: []byte("loop\n\n!include loop.md\n")},
+		"lines.md":  {Data: []byte("a\n\nb\n\nc\n")},
+		"refs.md":   {Data: []byte("[ref]: /url\n")},
+		"list.md":   {Data: []byte("item\n")},
+	}
+
+	runRenderTests(t, []renderTest{
+		{"!include intro.md\n!include refs.md", "<h1>Intro</h1>\n<p>Hello <a href=\"/url\">ref</a>.</p>\n"},
+		{"!include intro.md shift=1", "<h2>Intro</h2>\n<p>Hello [ref].</p>\n"},
+		{"{{< include \"ch/one.md\" >}}", "<h2>One</h2>\n<p>Two</p>\n"},
+		{"!include loop.md", "<p>loop</p>\n<p class=\"include-error\">!include loop.md</p>\n"},
+		{"!include lines.md lines=3-5", "<p>b</p>\n<p>c</p>\n"},
+		{"!include missing.md", "<p class=\"include-error\">!include missing.md</p>\n"},
+		{"text\n!include <b>.md", "<p>text</p>\n<p class=\"include-error\">!include &lt;b&gt;.md</p>\n"},
+		{"!includes missing.md", "<p>!includes missing.md</p>\n"},
+		{"text\n!include ch/two.md", "<p>text</p>\n<p>Two</p>\n"},
+		{"- !include list.md\n- b", "<ul>\n<li>item</li>\n<li>b</li>\n</ul>\n"},
+	}, Include(fsys))
+
+	tokens := New(Include(fsys)).Parse([]byte("x\n\n!include ch/two.md"))
+	open, ok := tokens[3].(*IncludeOpen)
+	if !ok || open.Path != "ch/two.md" || open.Map != [2]int{2, 3} {
+		t.Fatalf("want IncludeOpen{ch/two.md [2 3]}, got %#v", tokens[3])
+	}
+	if p, ok := tokens[4].(*ParagraphOpen); !ok || p.Map != [2]int{0, 1} {
+		t.Errorf("want an included paragraph mapped to [0 1], got %#v", tokens[4])
+	}
+}
+
+// countingFS counts the files opened in it.
+type countingFS struct {
+	fs.FS
+	opened int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opened++
+	return c.FS.Open(name)
+}
+
+func TestIncludeErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"loop.md": {Data: []byte("!include loop.md\n")},
+	}
+	for i := 0; i <= maxIncludeDepth; i++ {
+		fsys["deep"+strconv.Itoa(i)+".md"] = &fstest.MapFile{Data: []byte("!include deep" + strconv.Itoa(i+1) + ".md\n")}
+	}
+	fsys["deep9.md"] = &fstest.MapFile{Data: []byte("bottom\n")}
+
+	tests := []struct {
+		in   string
+		path string
+		err  error
+	}{
+		{"!include missing.md", "missing.md", fs.ErrNotExist},
+		{"!include loop.md", "loop.md", ErrIncludeCycle},
+		{"!include deep0.md", "deep8.md", ErrIncludeDepth},
+	}
+	md := New(Include(fsys))
+	for _, tt := range tests {
+		var found *IncludeError
+		for _, tok := range md.Parse([]byte(tt.in)) {
+			if e, ok := tok.(*IncludeError); ok {
+				found = e
+			}
+		}
+		if found == nil {
+			t.Errorf("%q: got no IncludeError", tt.in)
+		} else if found.Path != tt.path || !errors.Is(found, tt.err) {
+			t.Errorf("%q: got %v (%s), want %v (%s)", tt.in, found.Err, found.Path, tt.err, tt.path)
+		}
+	}
+
+	// A directive that interrupts a paragraph is checked without reading
+	// its file.
+	c := &countingFS{FS: fstest.MapFS{"a.md": {Data: []byte("a\n")}}}
+	New(Include(c)).Parse([]byte("text\n!include a.md\n"))
+	if c.opened != 1 {
+		t.Errorf("the included file was opened %d times, want 1", c.opened)
+	}
+}
@@ -0,0 +1,731 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errBadEdit = errors.New("markdown: edit range out of bounds")
+
+// Document is a parse result that can be updated by edits to its source
+// without parsing all of it again, for use in editors.
+//
+// The source is kept as top-level blocks, split at the Map line ranges of
+// their tokens; the lines between two blocks go with the first. An edit
+// reparses the blocks it touches, or only the item it touches in a list.
+// It takes in the block before them only if the edited text may run into
+// it: when they are not separated by a blank line, or when the first
+// edited line may continue it. It goes on past the edit until it reaches
+// an old block that still starts after a blank line. The tokens of the
+// other blocks are kept as they are, with their Map line numbers moved,
+// so that their identities can be used to diff the rendered output.
+type Document struct {
+	md     *Markdown
+	src    []byte
+	blocks []docBlock
+	refs   map[string]map[string]string
+	arena  *arena // nil unless in the LowAlloc mode; not used by Edit
+}
+
+type docBlock struct {
+	start, end int // byte offsets in the source
+	line       int // first line
+	lines      int
+	tokens     []Token
+	refs       map[string]map[string]string // definitions in the block
+}
+
+// ParseDocument parses src into a Document. Its tokens are the same as
+// those returned by Parse.
+func (m *Markdown) ParseDocument(src []byte) *Document {
+	d := &Document{md: m}
+	if m.LowAlloc {
+		d.arena = &arena{}
+	}
+	d.parse(src)
+	return d
+}
+
+// Reset parses src into the document again. In the LowAlloc mode it
+// reuses the memory of the old tokens, which must no longer be used.
+// The tokens made by Edit are allocated one by one, so that edits do not
+// leave dead tokens in the slabs until the next Reset.
+func (d *Document) Reset(src []byte) {
+	d.arena.reset()
+	d.parse(src)
+}
+
+func (d *Document) parse(src []byte) {
+	d.src = src
+	tokens, refs := d.blockParse(src, d.arena)
+	d.blocks = d.splitBlocks(src, 0, 0, tokens, refs)
+	d.refs = mergeRefs(d.blocks)
+	for i := range d.blocks {
+		d.finishBlock(&d.blocks[i], d.refs, d.arena)
+	}
+	d.numberFigures()
+}
+
+// Source returns the current source of the document.
+func (d *Document) Source() []byte { return d.src }
+
+// Tokens returns the tokens of the whole document.
+func (d *Document) Tokens() []Token {
+	var tokens []Token
+	for _, b := range d.blocks {
+		tokens = append(tokens, b.tokens...)
+	}
+	return tokens
+}
+
+// Edit replaces the bytes from start to end of the source with text and
+// updates the tokens. The source passed to ParseDocument is not
+// modified.
+func (d *Document) Edit(start, end int, text []byte) error {
+	if start < 0 || end < start || end > len(d.src) {
+		return errBadEdit
+	}
+
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+	delta := len(text) - (end - start)
+
+	first := 0
+	for first+1 < len(d.blocks) && d.blocks[first+1].start <= start {
+		first++
+	}
+	if d.editItem(first, src, start, end, delta) {
+		d.src = src
+		d.numberFigures()
+		return nil
+	}
+	for first > 0 && !d.startsBlock(src, first) {
+		first--
+	}
+	from, line := 0, 0
+	if len(d.blocks) > 0 {
+		from, line = d.blocks[first].start, d.blocks[first].line
+	}
+
+	// Reparse until an old block after the edit still starts a block;
+	// the rest of the source is the same.
+	next := first + 1
+	to := len(src)
+	var tokens []Token
+	var refs map[string]map[string]string
+	g := grouper{md: d.md}
+	for pos := from; pos < len(src); {
+		eol := len(src)
+		if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+			eol = pos + i + 1
+		}
+		for next < len(d.blocks) && (d.blocks[next].start < end || d.blocks[next].start+delta < pos) {
+			next++
+		}
+		if g.mayEnd(src[pos:eol]) && pos > from && next < len(d.blocks) && d.blocks[next].start+delta == pos {
+			tokens, refs = d.blockParse(src[from:pos], nil)
+			if g.ends(src[from:pos], tokens, src[pos:eol]) {
+				to = pos
+				break
+			}
+		}
+		pos = eol
+	}
+	if to == len(src) {
+		next = len(d.blocks)
+		tokens, refs = d.blockParse(src[from:], nil)
+	}
+	shiftMaps(tokens, line)
+	blocks := d.splitBlocks(src[:to], from, line, tokens, refs)
+	for _, b := range blocks {
+		line += b.lines
+	}
+
+	tail := d.blocks[next:]
+	if len(tail) > 0 {
+		if lineDelta := line - tail[0].line; lineDelta != 0 {
+			for i := range tail {
+				tail[i].line += lineDelta
+				shiftMaps(tail[i].tokens, lineDelta)
+			}
+		}
+		for i := range tail {
+			tail[i].start += delta
+			tail[i].end += delta
+		}
+	}
+
+	all := make([]docBlock, 0, first+len(blocks)+len(tail))
+	all = append(all, d.blocks[:first]...)
+	all = append(all, blocks...)
+	all = append(all, tail...)
+	d.src = src
+	d.blocks = all
+
+	refs = mergeRefs(all)
+	changed := changedRefs(d.refs, refs)
+	d.refs = refs
+
+	for i := range all {
+		b := &all[i]
+		if i >= first && i < first+len(blocks) {
+			d.finishBlock(b, refs, nil)
+		} else if len(changed) > 0 && b.mentions(changed) {
+			b.tokens, _ = d.blockParse(src[b.start:b.end], nil)
+			shiftMaps(b.tokens, b.line)
+			d.finishBlock(b, refs, nil)
+		}
+	}
+	d.numberFigures()
+
+	return nil
+}
+
+// editItem handles an edit inside one item of the list that is the
+// block i, parsing only that item. It reports false, leaving the
+// document as it is, if the edit may change more than the item: its
+// marker or indentation, whether the list is loose, the blocks around
+// the list, or the reference definitions.
+func (d *Document) editItem(i int, src []byte, start, end, delta int) bool {
+	if i >= len(d.blocks) {
+		return false
+	}
+	b := &d.blocks[i]
+	if end >= b.end || b.refs != nil {
+		return false
+	}
+	items := itemStarts(b.tokens)
+	if items == nil {
+		return false
+	}
+	items = append(items, len(b.tokens)-1)
+
+	// Find the item, and the offsets of the items in the source.
+	offsets := make([]int, len(items))
+	pos, line := b.start, b.line
+	for j := range items[:len(items)-1] {
+		m := b.tokens[items[j]].(*ListItemOpen).Map
+		pos = skipLines(d.src, pos, m[0]-line)
+		line = m[0]
+		offsets[j] = pos
+	}
+	offsets[len(items)-1] = b.end
+	n := 0
+	for n+1 < len(items)-1 && offsets[n+1] <= start {
+		n++
+	}
+	last := n == len(items)-2
+	itemStart, itemEnd := offsets[n], offsets[n+1]
+	if start < itemStart || end >= itemEnd {
+		return false
+	}
+	if !bytes.Equal(itemPrefix(lineAt(d.src[itemStart:], 0)), itemPrefix(lineAt(src[itemStart:], 0))) {
+		return false
+	}
+	if n == 0 && i > 0 && !d.startsBlock(src, i) {
+		return false
+	}
+	if last && i+1 < len(d.blocks) && !startsAfter(d.md, src, d.blocks[i+1].start+delta, b.tokens) {
+		return false
+	}
+
+	// Parse the item with the first line of the next one, which must
+	// still start an item, as lazy continuation lines may run on.
+	item := src[itemStart : itemEnd+delta]
+	with := item
+	if !last {
+		with = src[itemStart : itemEnd+delta+len(lineAt(d.src[itemEnd:], 0))]
+	}
+	tokens, refs := d.blockParse(with, nil)
+	starts := itemStarts(tokens)
+	if refs != nil || last && len(starts) != 1 || !last && len(starts) != 2 {
+		return false
+	}
+	if !last && tokens[starts[1]].(*ListItemOpen).Map[0] != countLines(item) {
+		return false
+	}
+
+	// A list is loose if one of its items is, or ends with a blank line
+	// before the next one; the paragraphs of a tight list are marked
+	// Tight.
+	oldTight, oldKnown := listTight(b.tokens)
+	tight, known := listTight(tokens)
+	switch {
+	case !known:
+		if oldKnown {
+			return false
+		}
+	case !oldKnown:
+		return false
+	case oldTight:
+		if !tight {
+			return false
+		}
+	case tight:
+		if !d.otherBlankEnds(b, items, offsets, n) {
+			return false
+		}
+		markTight(tokens, false)
+	}
+
+	shiftMaps(tokens, b.tokens[items[n]].(*ListItemOpen).Map[0])
+	s := &stateCore{md: d.md, env: &environment{References: d.refs}, tokens: tokens}
+	d.md.runCoreRules(s)
+	tokens = s.tokens
+	starts = append(itemStarts(tokens), len(tokens)-1)
+
+	lineDelta := countLines(item) - countLines(d.src[itemStart:itemEnd])
+	after := b.tokens[items[n+1]:]
+	shiftMaps(after, lineDelta)
+	all := make([]Token, 0, items[n]+starts[1]-1+len(after))
+	all = append(all, b.tokens[:items[n]]...)
+	all = append(all, tokens[1:starts[1]]...)
+	all = append(all, after...)
+
+	listMap := mapOf(all[0])
+	if last {
+		listMap[1] = mapOf(tokens[0])[1]
+	} else {
+		listMap[1] += lineDelta
+	}
+	if list, ok := all[0].(*OrderedListOpen); ok && n == 0 {
+		list.Order = tokens[0].(*OrderedListOpen).Order
+	}
+
+	b.tokens = all
+	b.end += delta
+	b.lines += lineDelta
+	for j := i + 1; j < len(d.blocks); j++ {
+		next := &d.blocks[j]
+		next.start += delta
+		next.end += delta
+		next.line += lineDelta
+		shiftMaps(next.tokens, lineDelta)
+	}
+	return true
+}
+
+// otherBlankEnds reports whether an item of the list, other than the nth,
+// ends with a blank line before the next one, which makes the list
+// loose.
+func (d *Document) otherBlankEnds(b *docBlock, items, offsets []int, n int) bool {
+	for k := 0; k+2 < len(items); k++ {
+		if k != n && endsBlank(d.src[offsets[k]:offsets[k+1]], b.tokens[items[k]:items[k+1]-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemStarts returns the indexes of the items in the block tokens of a
+// list, or nil if the tokens are not those of a single list.
+func itemStarts(tokens []Token) []int {
+	if len(tokens) < 2 {
+		return nil
+	}
+	switch tokens[0].(type) {
+	case *BulletListOpen, *OrderedListOpen:
+	default:
+		return nil
+	}
+	var starts []int
+	for i, tok := range tokens[1 : len(tokens)-1] {
+		if tok.Level() < 1 {
+			return nil
+		}
+		if _, ok := tok.(*ListItemOpen); ok && tok.Level() == 1 {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// listTight reports whether the list whose tokens start tokens is tight,
+// if it has paragraphs that tell.
+func listTight(tokens []Token) (tight, known bool) {
+	level := tokens[0].Level() + 2
+	for _, tok := range tokens {
+		if p, ok := tok.(*ParagraphOpen); ok && p.Lvl == level {
+			return p.Tight, true
+		}
+	}
+	return false, false
+}
+
+// markTight sets whether the paragraphs of the list whose tokens start
+// tokens are tight.
+func markTight(tokens []Token, tight bool) {
+	level := tokens[0].Level() + 2
+	for _, tok := range tokens {
+		switch tok := tok.(type) {
+		case *ParagraphOpen:
+			if tok.Lvl == level {
+				tok.Tight = tight
+			}
+		case *ParagraphClose:
+			if tok.Lvl == level {
+				tok.Tight = tight
+			}
+		}
+	}
+}
+
+// endsBlank reports whether the list item with the source src and the
+// tokens up to its closing one ends with a blank line, as the list rule
+// sees it.
+func endsBlank(src []byte, tokens []Token) bool {
+	if countLines(src) < 2 || !isBlankLine(lineBefore(src, len(src))) {
+		return false
+	}
+	_, quote := tokens[len(tokens)-1].(*BlockquoteClose)
+	return !quote
+}
+
+// itemPrefix returns the start of the line up to the content of the list
+// item it starts, or the whole line if the item starts empty; these tell
+// the marker and the indentation of the item. It returns nil if the line
+// does not start an item.
+func itemPrefix(line []byte) []byte {
+	m, ok := listMarkerOf(line)
+	if !ok {
+		return nil
+	}
+	pos := len(line) - len(bytes.TrimLeft(line, " "))
+	if m.ordered {
+		pos += bytes.IndexByte(line[pos:], m.char)
+	}
+	pos++
+	for pos < len(line) && line[pos] == ' ' {
+		pos++
+	}
+	if isBlankLine(line[pos:]) {
+		return line
+	}
+	return line[:pos]
+}
+
+// startsBlock reports whether the block i still starts a block of its
+// own after an edit that leaves the source before it as it is.
+func (d *Document) startsBlock(src []byte, i int) bool {
+	return i == 0 || startsAfter(d.md, src, d.blocks[i].start, d.blocks[i-1].tokens)
+}
+
+// startsAfter reports whether the line at pos in src starts a block
+// whatever the source after it, given the tokens of the block before: it
+// follows a blank line, and is not blank, indented, an item marker after
+// a list, or a table caption.
+func startsAfter(md *Markdown, src []byte, pos int, prev []Token) bool {
+	if pos >= len(src) || pos > 0 && src[pos-1] != '\n' && src[pos-1] != '\r' {
+		return false
+	}
+	if !isBlankLine(lineBefore(src, pos)) {
+		return false
+	}
+	line := lineAt(src[pos:], 0)
+	if isBlankLine(line) || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+	if md.ExtendedTables && bytes.HasPrefix(line, []byte("Table:")) {
+		return false
+	}
+	if _, ok := listMarkerOf(line); ok && len(prev) > 0 {
+		switch prev[0].(type) {
+		case *BulletListOpen, *OrderedListOpen:
+			return false
+		}
+	}
+	return true
+}
+
+// lineBefore returns the line ending at pos in src, which must be the
+// start of a line.
+func lineBefore(src []byte, pos int) []byte {
+	i := pos - 1
+	if i > 0 && src[i] == '\n' && src[i-1] == '\r' {
+		i--
+	}
+	for i > 0 && src[i-1] != '\n' && src[i-1] != '\r' {
+		i--
+	}
+	if i < 0 {
+		i = 0
+	}
+	return src[i:pos]
+}
+
+// skipLines returns the offset n lines after pos in src, counting lines
+// the way countLines does.
+func skipLines(src []byte, pos, n int) int {
+	for ; n > 0 && pos < len(src); pos++ {
+		switch src[pos] {
+		case '\r':
+			if pos+1 < len(src) && src[pos+1] == '\n' {
+				pos++
+			}
+			n--
+		case '\n':
+			n--
+		}
+	}
+	return pos
+}
+
+// splitBlocks splits the block tokens parsed from src[from:], whose
+// first line is line, into top-level blocks. The reference definitions
+// refs of the source are split between the blocks as well.
+func (d *Document) splitBlocks(src []byte, from, line int, tokens []Token, refs map[string]map[string]string) []docBlock {
+	if from >= len(src) && len(tokens) == 0 {
+		return nil
+	}
+	var blocks []docBlock
+	b := docBlock{start: from, line: line}
+	first, depth := 0, 0
+	for i, tok := range tokens {
+		if depth == 0 && i > 0 {
+			if m := mapOf(tok); m != nil && m[0] > b.line {
+				b.end = skipLines(src, b.start, m[0]-b.line)
+				b.lines = m[0] - b.line
+				b.tokens = tokens[first:i:i]
+				blocks = append(blocks, b)
+				b = docBlock{start: b.end, line: m[0]}
+				first = i
+			}
+		}
+		if tok.Opening() {
+			depth++
+		} else if tok.Closing() {
+			depth--
+		}
+	}
+	b.end = len(src)
+	b.lines = countLines(src[b.start:])
+	b.tokens = tokens[first:len(tokens):len(tokens)]
+	blocks = append(blocks, b)
+
+	if len(blocks) == 1 {
+		blocks[0].refs = refs
+	} else if refs != nil {
+		for i := range blocks {
+			if span := src[blocks[i].start:blocks[i].end]; bytes.Contains(span, []byte("]:")) {
+				env := &environment{}
+				d.md.block.parse(span, d.md, env)
+				blocks[i].refs = env.References
+			}
+		}
+	}
+	return blocks
+}
+
+// blockParse runs the block rules over src and returns its tokens and
+// reference definitions. The tokens are allocated from the arena a, if
+// not nil.
+func (d *Document) blockParse(src []byte, a *arena) ([]Token, map[string]map[string]string) {
+	env := &environment{arena: a}
+	return d.md.block.parse(src, d.md, env), env.References
+}
+
+// finishBlock runs the core rules over the block tokens of the block,
+// with the reference definitions of the whole document, allocating from
+// the arena a if not nil.
+func (d *Document) finishBlock(b *docBlock, refs map[string]map[string]string, a *arena) {
+	s := &stateCore{md: d.md, env: &environment{References: refs, arena: a}, tokens: b.tokens}
+	d.md.runCoreRules(s)
+	b.tokens = s.tokens
+}
+
+// mergeRefs collects the reference definitions of the blocks; the first
+// definition of a label wins, as in a single parse.
+func mergeRefs(blocks []docBlock) map[string]map[string]string {
+	var refs map[string]map[string]string
+	for _, b := range blocks {
+		for label, ref := range b.refs {
+			if refs == nil {
+				refs = make(map[string]map[string]string)
+			}
+			if _, ok := refs[label]; !ok {
+				refs[label] = ref
+			}
+		}
+	}
+	return refs
+}
+
+// changedRefs returns the labels that are defined differently, or only
+// in one of old and refs.
+func changedRefs(old, refs map[string]map[string]string) []string {
+	var labels []string
+	for label, ref := range refs {
+		if o, ok := old[label]; !ok || o["href"] != ref["href"] || o["title"] != ref["title"] {
+			labels = append(labels, label)
+		}
+	}
+	for label := range old {
+		if _, ok := refs[label]; !ok {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// mentions reports whether the inline content of the block may use one
+// of the (normalized) labels.
+func (b *docBlock) mentions(labels []string) bool {
+	for _, tok := range b.tokens {
+		inline, ok := tok.(*Inline)
+		if !ok {
+			continue
+		}
+		content := normalizeReference(inline.Content)
+		for _, label := range labels {
+			if strings.Contains(content, label) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numberFigures numbers the labeled figures across the blocks.
+func (d *Document) numberFigures() {
+	if d.md.FigureLabel == "" {
+		return
+	}
+	n := 0
+	for _, b := range d.blocks {
+		for _, tok := range b.tokens {
+			switch tok := tok.(type) {
+			case *FigureOpen:
+				if tok.Label != "" {
+					n++
+					tok.Number = n
+					tok.ID = "fig-" + strconv.Itoa(n)
+				}
+			case *FigureClose:
+				if tok.Label != "" {
+					tok.Number = n
+				}
+			}
+		}
+	}
+}
+
+// shiftMaps adds delta to the Map line numbers of the tokens. The
+// contents of included files and of grid table cells are parsed on their
+// own and keep their line numbers.
+func shiftMaps(tokens []Token, delta int) {
+	if delta == 0 {
+		return
+	}
+	includes := 0
+	cell := -1
+	for _, tok := range tokens {
+		switch tok.(type) {
+		case *IncludeOpen:
+			includes++
+			if includes > 1 {
+				continue
+			}
+		case *IncludeClose:
+			includes--
+			continue
+		default:
+			if includes > 0 {
+				continue
+			}
+		}
+
+		if cell >= 0 {
+			if tok.Closing() && tok.Level() == cell {
+				cell = -1
+				continue
+			}
+			// The inline content of a pipe table cell has the line of
+			// its row.
+			if _, ok := tok.(*Inline); !ok || tok.Level() != cell+1 {
+				continue
+			}
+		}
+		switch tok.(type) {
+		case *ThOpen, *TdOpen:
+			cell = tok.Level()
+		}
+
+		m := mapOf(tok)
+		if m == nil || *m == [2]int{} {
+			// Not set, as for the rows of a table body.
+			continue
+		}
+		m[0] += delta
+		m[1] += delta
+	}
+}
+
+// mapOf returns the Map field of the token, or nil.
+func mapOf(tok Token) *[2]int {
+	switch tok := tok.(type) {
+	case *BlockquoteOpen:
+		return &tok.Map
+	case *BulletListOpen:
+		return &tok.Map
+	case *OrderedListOpen:
+		return &tok.Map
+	case *ListItemOpen:
+		return &tok.Map
+	case *CaptionOpen:
+		return &tok.Map
+	case *CodeBlock:
+		return &tok.Map
+	case *ContainerOpen:
+		return &tok.Map
+	case *Fence:
+		return &tok.Map
+	case *FigureOpen:
+		return &tok.Map
+	case *HeadingOpen:
+		return &tok.Map
+	case *HTMLBlock:
+		return &tok.Map
+	case *Hr:
+		return &tok.Map
+	case *IncludeOpen:
+		return &tok.Map
+	case *IncludeError:
+		return &tok.Map
+	case *Inline:
+		return &tok.Map
+	case *ParagraphOpen:
+		return &tok.Map
+	case *ParagraphClose:
+		return &tok.Map
+	case *TableOpen:
+		return &tok.Map
+	case *TheadOpen:
+		return &tok.Map
+	case *TrOpen:
+		return &tok.Map
+	case *ThOpen:
+		return &tok.Map
+	case *TbodyOpen:
+		return &tok.Map
+	case *TdOpen:
+		return &tok.Map
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package markdown
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type docEdit struct {
+	start, end int
+	text       string
+}
+
+func checkDocument(t *testing.T, md *Markdown, d *Document) {
+	t.Helper()
+	want := md.Parse(d.Source())
+	if got := d.Tokens(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after edits to %q:\ngot  %s\nwant %s", d.Source(),
+			md.RenderTokensToString(got), md.RenderTokensToString(want))
+	}
+}
+
+func TestDocumentEdit(t *testing.T) {
+	tests := []struct {
+		src   string
+		edits []docEdit
+	}{
+		{"# A\n\ntext\n\nmore\n", []docEdit{{6, 10, "changed"}, {0, 1, "##"}}},
+		{"a\n\nb\n\nc\n", []docEdit{{1, 3, "\n"}}},
+		{"a\n\nb\n\nc\n", []docEdit{{3, 3, "```\n"}}},
+		{"a\n\n```\nb\n\nc\n", []docEdit{{3, 6, ""}}},
+		{"- a\n\nb\n\nc\n", []docEdit{{5, 5, "- "}}},
+		{"text\n\nmore\n", []docEdit{{6, 6, "===\n\n"}, {4, 4, "\n---"}}},
+		{"[x] and [y]\n\ntext\n\n[x]: /x\n", []docEdit{{19, 26, ""}, {19, 19, "[y]: /y\n"}}},
+		{"[a]\n\n[a]: /1\n\n[a]: /2\n", []docEdit{{5, 14, ""}}},
+		{"![a](/a \"A\")\n\ntext\n\n![b](/b \"B\")\n", []docEdit{{0, 14, ""}, {0, 0, "![c](/c \"C\")\n\n"}}},
+		{"a\r\n\r\nb\rc\r\n\r\nd\r\n", []docEdit{{3, 3, "x"}}},
+		{"", []docEdit{{0, 0, "# new\n"}, {0, 6, ""}}},
+		{"- a\n- b\n- c\n", []docEdit{{5, 6, "x"}, {5, 5, "\n"}, {5, 6, ""}, {9, 9, "\n  more"}}},
+		{"- a\n\n- b\n\n- c\n", []docEdit{{8, 9, ""}, {3, 4, ""}, {6, 6, "\n"}, {0, 1, "*"}}},
+		{"1. a\n2. b\n\ntext\n", []docEdit{{0, 1, "3"}, {9, 10, ""}, {8, 8, "```"}}},
+		{"- a\n  > q\n- b\n", []docEdit{{8, 9, "<div>"}}},
+	}
+	md := New(HTML(true), Figures(true), NumberFigures("Figure"))
+	for _, tt := range tests {
+		d := md.ParseDocument([]byte(tt.src))
+		checkDocument(t, md, d)
+		for _, e := range tt.edits {
+			if err := d.Edit(e.start, e.end, []byte(e.text)); err != nil {
+				t.Fatal(err)
+			}
+			checkDocument(t, md, d)
+		}
+	}
+}
+
+func TestDocumentEditRandom(t *testing.T) {
+	pieces := []string{
+		"# h\n", "text\n", "\n", "\n\n", "- item\n", "1. one\n", "    code\n", "```\n",
+		"> quote\n", "[r]\n", "[r]: /r\n", "===\n", "---\n", "a | b\n--|--\n", "<div>\n",
+		"*emph*", "  ", "x", "::: note\n", ":::\n", "Table: cap\n", "![i](/i \"T\")\n",
+		"> [!NOTE]\n", "+---+\n| a |\n+---+\n", "!include part.md\n", "* star\n", "  - sub\n", "<!--\n", "-->\n", "<pre>\n",
+	}
+	fsys := fstest.MapFS{"part.md": {Data: []byte("# part\n\n+---+\n| b |\n+---+\n")}}
+	md := New(HTML(true), ExtendedTables(true), GridTables(true), Containers(true), Alerts(true),
+		Figures(true), NumberFigures("Figure"), Include(fsys))
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var src strings.Builder
+		for j := rnd.Intn(12); j >= 0; j-- {
+			src.WriteString(pieces[rnd.Intn(len(pieces))])
+		}
+		d := md.ParseDocument([]byte(src.String()))
+		for j := 0; j < 5; j++ {
+			n := len(d.Source())
+			start := rnd.Intn(n + 1)
+			end := start + rnd.Intn(n-start+1)/2
+			text := ""
+			if rnd.Intn(3) > 0 {
+				text = pieces[rnd.Intn(len(pieces))]
+			}
+			if err := d.Edit(start, end, []byte(text)); err != nil {
+				t.Fatal(err)
+			}
+			checkDocument(t, md, d)
+		}
+	}
+}
+
+func TestDocumentStableTokens(t *testing.T) {
+	md := New()
+	d := md.ParseDocument([]byte("# Title\n\nfirst\n\nsecond\n\nthird\n"))
+	before := d.Tokens()
+
+	if err := d.Edit(16, 22, []byte("2nd\n\nand a half")); err != nil {
+		t.Fatal(err)
+	}
+	after := d.Tokens()
+
+	// Only the edited paragraph is parsed again.
+	for i := 0; i < 6; i++ {
+		if after[i] != before[i] {
+			t.Errorf("token %d of the heading or the first paragraph was replaced", i)
+		}
+	}
+	last, lastBefore := after[len(after)-3:], before[len(before)-3:]
+	for i := range last {
+		if last[i] != lastBefore[i] {
+			t.Errorf("token %d of the last paragraph was replaced", i)
+		}
+	}
+	if m := last[0].(*ParagraphOpen).Map; m != [2]int{8, 9} {
+		t.Errorf("Map of the last paragraph is %v, want [8 9]", m)
+	}
+
+	if err := d.Edit(0, 0, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Edit(-1, 0, nil); err == nil {
+		t.Error("Edit(-1, 0): got no error")
+	}
+}
+
+func TestDocumentEditItem(t *testing.T) {
+	md := New()
+	d := md.ParseDocument([]byte("- one\n- two\n- three\n\nafter\n"))
+	before := d.Tokens()
+
+	if err := d.Edit(8, 11, []byte("2\n  and a half")); err != nil {
+		t.Fatal(err)
+	}
+	checkDocument(t, md, d)
+	after := d.Tokens()
+
+	// Only the edited item is parsed again; the list keeps its tokens.
+	if after[0] != before[0] {
+		t.Error("the list was replaced")
+	}
+	for i := 1; i < 6; i++ {
+		if after[i] != before[i] {
+			t.Errorf("token %d of the first item was replaced", i)
+		}
+	}
+	if after[6] == before[6] {
+		t.Error("the edited item was kept")
+	}
+	for i := 1; i <= 9; i++ {
+		if after[len(after)-i] != before[len(before)-i] {
+			t.Errorf("token %d from the end was replaced", i)
+		}
+	}
+}
@@ -22,44 +22,64 @@ func init() {
 }
 
 func ruleLHeading(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
-	nextLine := startLine + 1
-
-	if nextLine >= endLine {
-		return
-	}
-
-	shift := s.tShift[nextLine]
-	if shift < s.blkIndent {
-		return
-	}
-
-	if shift-s.blkIndent > 3 {
+	if s.sCount[startLine]-s.blkIndent > 3 {
 		return
 	}
 
-	pos := s.bMarks[nextLine] + shift
-	max := s.eMarks[nextLine]
-
-	if pos >= max {
-		return
-	}
+	oldParentType := s.parentType
+	s.parentType = ptParagraph
 
 	src := s.src
-	marker := src[pos]
+	var marker byte
+	nextLine := startLine + 1
 
-	if !under[marker] {
-		return
+outer:
+	for ; nextLine < endLine && !s.isLineEmpty(nextLine); nextLine++ {
+		shift := s.sCount[nextLine]
+		if shift-s.blkIndent > 3 {
+			continue
+		}
+
+		if shift >= s.blkIndent {
+			pos := s.bMarks[nextLine] + s.tShift[nextLine]
+			max := s.eMarks[nextLine]
+
+			if under[src[pos]] {
+				end := s.skipSpaces(s.skipBytes(pos, src[pos]))
+				if end >= max {
+					marker = src[pos]
+					break
+				}
+			}
+		}
+
+		if shift < 0 {
+			continue
+		}
+
+		for _, r := range []blockRule{
+			ruleFence,
+			ruleInclude,
+			ruleContainer,
+			ruleBlockQuote,
+			ruleHR,
+			ruleList,
+			ruleHeading,
+			ruleHTMLBlock,
+			ruleTable,
+		} {
+			if r(s, nextLine, endLine, true) {
+				break outer
+			}
+		}
 	}
+	s.parentType = oldParentType
 
-	pos = s.skipBytes(pos, marker)
-
-	pos = s.skipSpaces(pos)
-
-	if pos < max {
+	if marker == 0 {
 		return
 	}
 
-	pos = s.bMarks[startLine] + s.tShift[startLine]
+	content := strings.TrimSpace(s.lines(startLine, nextLine, s.blkIndent, false))
 
 	s.line = nextLine + 1
 
@@ -73,7 +93,7 @@ func ruleLHeading(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 		Map:    [2]int{startLine, s.line},
 	})
 	s.pushToken(&Inline{
-		Content: strings.TrimSpace(src[pos:s.eMarks[startLine]]),
+		Content: content,
 		Map:     [2]int{startLine, s.line - 1},
 	})
 	s.pushClosingToken(&HeadingClose{HLevel: hLevel})
@@ -0,0 +1,134 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limit names a resource limit of ParseContext.
+type Limit int
+
+const (
+	LimitBytes   Limit = iota // input size, set by MaxBytes
+	LimitLines                // number of lines, set by MaxLines
+	LimitTokens               // number of tokens, set by MaxTokens
+	LimitNesting              // block and inline nesting, set by MaxNesting
+)
+
+var limitNames = [...]string{"bytes", "lines", "tokens", "nesting"}
+
+func (l Limit) String() string {
+	if int(l) < len(limitNames) {
+		return limitNames[l]
+	}
+	return fmt.Sprintf("Limit(%d)", int(l))
+}
+
+// LimitError is returned by ParseContext when the input exceeds one of
+// the limits.
+type LimitError struct {
+	Limit Limit
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("markdown: input exceeds the %s limit of %d", e.Limit, e.Max)
+}
+
+// countLines counts the lines of src the way normalizeAndIndex breaks
+// them, at \n, \r\n and a lone \r.
+func countLines(src []byte) int {
+	n := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\r':
+			if i+1 < len(src) && src[i+1] == '\n' {
+				i++
+			}
+			n++
+		case '\n':
+			n++
+		}
+	}
+	if len(src) > 0 && src[len(src)-1] != '\n' && src[len(src)-1] != '\r' {
+		n++
+	}
+	return n
+}
+
+// How many times stopped is called between two checks of the context.
+const checkInterval = 256
+
+// limiter enforces the limits and the cancellation of a ParseContext.
+type limiter struct {
+	ctx       context.Context
+	maxTokens int
+	tokens    int
+	calls     int
+	err       error
+}
+
+func (l *limiter) fail(err error) {
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// stopped reports whether the parse has to stop, either because a limit
+// was hit or because the context is done.
+func (l *limiter) stopped() bool {
+	if l.err != nil {
+		return true
+	}
+	l.calls++
+	if l.calls%checkInterval == 0 {
+		if err := l.ctx.Err(); err != nil {
+			l.err = err
+			return true
+		}
+	}
+	return false
+}
+
+// stopped reports whether a ParseContext has to stop. It is always false
+// for Parse.
+func (e *environment) stopped() bool {
+	return e != nil && e.limits != nil && e.limits.stopped()
+}
+
+// countToken counts a token pushed to any of the block or inline states
+// against the MaxTokens limit.
+func (e *environment) countToken() { e.countTokens(1) }
+
+// countTokens counts n tokens added by a core rule against the MaxTokens
+// limit.
+func (e *environment) countTokens(n int) {
+	if e == nil || e.limits == nil || e.limits.maxTokens <= 0 {
+		return
+	}
+	e.limits.tokens += n
+	if e.limits.tokens > e.limits.maxTokens {
+		e.limits.fail(&LimitError{LimitTokens, e.limits.maxTokens})
+	}
+}
+
+// nestingExceeded records a LimitError when a ParseContext reaches
+// MaxNesting. Parse keeps treating the too deeply nested markup as text.
+func (e *environment) nestingExceeded(max int) {
+	if e != nil && e.limits != nil {
+		e.limits.fail(&LimitError{LimitNesting, max})
+	}
+}
@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseContextLimits(t *testing.T) {
+	type testCase struct {
+		in    string
+		opts  []option
+		limit Limit
+	}
+	testCases := []testCase{
+		{"0123456789", []option{MaxBytes(9)}, LimitBytes},
+		{"a\nb\nc", []option{MaxLines(2)}, LimitLines},
+		{"- a\n- b\n- c\n- d\n", []option{MaxTokens(10)}, LimitTokens},
+		{strings.Repeat("> ", 30) + "a\n\nb", nil, LimitNesting},
+		{strings.Repeat("- ", 30) + "a", nil, LimitNesting},
+		{strings.Repeat("*a ", 30) + strings.Repeat("a*", 30), []option{MaxNesting(5)}, LimitNesting},
+
+		// Tokens added by the core rules count too.
+		{strings.Repeat("http://a.com ", 50), []option{MaxTokens(100)}, LimitTokens},
+		{strings.Repeat("www.a.com ", 50), []option{GFM(true), MaxTokens(100)}, LimitTokens},
+	}
+	for _, tc := range testCases {
+		md := New(tc.opts...)
+		tokens, err := md.ParseContext(context.Background(), []byte(tc.in))
+		var lerr *LimitError
+		if !errors.As(err, &lerr) {
+			t.Errorf("ParseContext(%q): got error %v, want a %s LimitError", tc.in, err, tc.limit)
+			continue
+		}
+		if lerr.Limit != tc.limit {
+			t.Errorf("ParseContext(%q): got the %s limit, want %s", tc.in, lerr.Limit, tc.limit)
+		}
+		if tokens != nil {
+			t.Errorf("ParseContext(%q): got %d tokens, want none", tc.in, len(tokens))
+		}
+	}
+}
+
+func TestParseContextWithinLimits(t *testing.T) {
+	src := []byte("# Title\n\n- a\n- *b*\n\n> c\n")
+	md := New(MaxBytes(len(src)), MaxLines(6), MaxTokens(40))
+	tokens, err := md.ParseContext(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := md.RenderTokensToString(tokens), md.RenderToString(src); got != want {
+		t.Errorf("ParseContext renders %q, want %q", got, want)
+	}
+
+	links := []byte(strings.Repeat("http://a.com ", 20))
+	md = New(MaxTokens(100))
+	tokens, err = md.ParseContext(context.Background(), links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := md.RenderTokensToString(tokens), md.RenderToString(links); got != want {
+		t.Errorf("ParseContext renders %q, want %q", got, want)
+	}
+
+	nested := []byte(strings.Repeat("> ", 30) + "a")
+	if got := md.RenderToString(nested); !strings.HasPrefix(got, "<blockquote>") {
+		t.Errorf("Parse of deeply nested input renders %q", got)
+	}
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	md := New()
+	if _, err := md.ParseContext(ctx, []byte("a")); err != context.Canceled {
+		t.Errorf("ParseContext with a canceled context: got error %v, want %v", err, context.Canceled)
+	}
+
+	var b strings.Builder
+	if err := md.RenderContext(ctx, &b, []byte("a")); err != context.Canceled {
+		t.Errorf("RenderContext with a canceled context: got error %v, want %v", err, context.Canceled)
+	}
+	if b.Len() != 0 {
+		t.Errorf("RenderContext with a canceled context wrote %q", b.String())
+	}
+}
+
+func TestParseContextStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	md := New(RewriteURLs(func(url string, _ URLContext) string {
+		cancel()
+		return url
+	}))
+
+	src := []byte(strings.Repeat("- [a](/x) *b* `c`\n  > d\n", 1000))
+	if _, err := md.ParseContext(ctx, src); err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
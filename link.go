@@ -31,46 +31,46 @@ func ruleLink(s *stateInline, silent bool) (_ bool) {
 	oldPos := pos
 	pos = labelEnd + 1
 	max := s.posMax
+	parseReference := true
 	if pos < max && src[pos] == '(' {
+		parseReference = false
 		pos = skipws(src, pos+1, max)
 		if pos >= max {
 			return
 		}
 
-		url, endpos, ok := parseLinkDestination(src, pos, s.posMax)
+		url, endpos, ok := s.scanLinkDestination(pos)
 		if ok {
 			url = normalizeLink(url)
-			if validateLink(url) {
+			if s.md.allowsURL(url, URLLink) {
 				href = url
 				pos = endpos
 			}
-		}
-
-		start := pos
-		pos = skipws(src, pos, max)
-		if pos >= max {
-			return
-		}
 
-		title, _, endpos, ok = parseLinkTitle(src, pos, s.posMax)
-		if pos < max && start != pos && ok {
-			pos = skipws(src, endpos, max)
+			start := pos
+			pos = skipws(src, pos, max)
+			if pos < max && start != pos {
+				if t, _, endpos, ok := parseLinkTitle(src, pos, s.posMax); ok {
+					title = t
+					pos = skipws(src, endpos, max)
+				}
+			}
 		}
 
 		if pos >= max || src[pos] != ')' {
-			s.pos = oldPos
-			return
+			// Not an inline link; try a shortcut reference instead.
+			parseReference = true
+			href, title = "", ""
 		}
 
 		pos++
+	}
 
-	} else {
+	if parseReference {
 		if s.env.References == nil {
 			return
 		}
 
-		pos = skipws(src, pos, max)
-
 		if pos < max && src[pos] == '[' {
 			start := pos + 1
 			pos = parseLinkLabel(s, pos, false)
@@ -89,7 +89,7 @@ func ruleLink(s *stateInline, silent bool) (_ bool) {
 		}
 
 		ref, ok := s.env.References[normalizeReference(label)]
-		if !ok {
+		if !ok || !s.md.allowsURL(ref["href"], URLLink) {
 			s.pos = oldPos
 			return
 		}
@@ -103,7 +103,7 @@ func ruleLink(s *stateInline, silent bool) (_ bool) {
 		s.posMax = labelEnd
 
 		s.pushOpeningToken(&LinkOpen{
-			Href:  href,
+			Href:  s.md.rewriteURL(href, URLLink),
 			Title: title,
 		})
 
@@ -28,112 +28,159 @@ func isLinkClose(s string) bool {
 	return s[1] == '/'
 }
 
-func ruleLinkify(s *stateCore) {
-	blockTokens := s.tokens
+// replaceUnlinkedText calls fn for every text token of tok that is not
+// inside a link (either markdown or raw HTML <a>) and splices the returned
+// nodes in place of the text token. A nil result leaves the token as is.
+func replaceUnlinkedText(tok *Inline, env *environment, fn func(*Text) []Token) {
+	tokens := tok.Children
 
-	if !s.md.Linkify {
-		return
-	}
+	htmlLinkLevel := 0
 
-	for _, tok := range blockTokens {
-		if tok, ok := tok.(*Inline); ok {
-			tokens := tok.Children
-
-			htmlLinkLevel := 0
-
-			for i := len(tokens) - 1; i >= 0; i-- {
-				currentTok := tokens[i]
-
-				if _, ok := currentTok.(*LinkClose); ok {
-					i--
-					for tokens[i].Level() != currentTok.Level() {
-						if _, ok := tokens[i].(*LinkOpen); ok {
-							break
-						}
-						i--
-					}
-					continue
-				}
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if env.stopped() {
+			return
+		}
+		currentTok := tokens[i]
 
-				if currentTok, ok := currentTok.(*HTMLInline); ok {
-					if isLinkOpen(currentTok.Content) && htmlLinkLevel > 0 {
-						htmlLinkLevel--
-					}
-					if isLinkClose(currentTok.Content) {
-						htmlLinkLevel++
-					}
-				}
-				if htmlLinkLevel > 0 {
-					continue
+		if _, ok := currentTok.(*LinkClose); ok {
+			i--
+			for tokens[i].Level() != currentTok.Level() {
+				if _, ok := tokens[i].(*LinkOpen); ok {
+					break
 				}
+				i--
+			}
+			continue
+		}
 
-				if currentTok, ok := currentTok.(*Text); ok {
-					text := currentTok.Content
-					links := linkify.Links(text)
-					if len(links) == 0 {
-						continue
-					}
-
-					var nodes []Token
-					level := currentTok.Lvl
-					lastPos := 0
-
-					for _, ln := range links {
-						urlText := text[ln.Start:ln.End]
-						url := urlText
-						if ln.Schema == "" {
-							url = "http://" + url
-						} else if ln.Schema == "mailto:" && !strings.HasPrefix(url, "mailto:") {
-							url = "mailto:" + url
-						}
-						url = normalizeLink(url)
-						if !validateLink(url) {
-							continue
-						}
-
-						urlText = normalizeLinkText(urlText)
-
-						pos := ln.Start
-
-						if pos > lastPos {
-							tok := Text{
-								Content: text[lastPos:pos],
-								Lvl:     level,
-							}
-							nodes = append(nodes, &tok)
-						}
-
-						nodes = append(nodes, &LinkOpen{
-							Href: url,
-							Lvl:  level,
-						})
-						nodes = append(nodes, &Text{
-							Content: urlText,
-							Lvl:     level + 1,
-						})
-						nodes = append(nodes, &LinkClose{
-							Lvl: level,
-						})
-
-						lastPos = ln.End
-					}
-
-					if lastPos < len(text) {
-						tok := Text{
-							Content: text[lastPos:],
-							Lvl:     level,
-						}
-						nodes = append(nodes, &tok)
-					}
-
-					children := make([]Token, len(tokens)+len(nodes)-1)
-					copy(children, tokens[:i])
-					copy(children[i:], nodes)
-					copy(children[i+len(nodes):], tokens[i+1:])
-					tok.Children = children
-					tokens = children
-				}
+		if currentTok, ok := currentTok.(*HTMLInline); ok {
+			if isLinkOpen(currentTok.Content) && htmlLinkLevel > 0 {
+				htmlLinkLevel--
+			}
+			if isLinkClose(currentTok.Content) {
+				htmlLinkLevel++
+			}
+		}
+		if htmlLinkLevel > 0 {
+			continue
+		}
+
+		if currentTok, ok := currentTok.(*Text); ok {
+			nodes := fn(currentTok)
+			if nodes == nil {
+				continue
+			}
+
+			env.countTokens(len(nodes) - 1)
+
+			children := make([]Token, len(tokens)+len(nodes)-1)
+			copy(children, tokens[:i])
+			copy(children[i:], nodes)
+			copy(children[i+len(nodes):], tokens[i+1:])
+			tok.Children = children
+			tokens = children
+		}
+	}
+}
+
+func linkifyText(md *Markdown, currentTok *Text) []Token {
+	var links []autoLink
+	for _, ln := range linkify.Links(currentTok.Content) {
+		url := currentTok.Content[ln.Start:ln.End]
+		if ln.Schema == "" {
+			url = "http://" + url
+		} else if ln.Schema == "mailto:" && !strings.HasPrefix(url, "mailto:") {
+			url = "mailto:" + url
+		}
+		links = append(links, autoLink{ln.Start, ln.End, url})
+	}
+	return autoLinkNodes(md, currentTok, links, true)
+}
+
+func gfmLinkifyText(md *Markdown, currentTok *Text) []Token {
+	return autoLinkNodes(md, currentTok, findGFMAutolinks(currentTok.Content), false)
+}
+
+// autoLinkNodes splits the text token into the text and link tokens of
+// the links found in it. With unescape set, percent-encoded link texts are
+// decoded.
+func autoLinkNodes(md *Markdown, currentTok *Text, links []autoLink, unescape bool) []Token {
+	if len(links) == 0 {
+		return nil
+	}
+
+	var nodes []Token
+	text := currentTok.Content
+	level := currentTok.Lvl
+	lastPos := 0
+
+	for _, ln := range links {
+		url := normalizeLink(ln.href)
+		if !md.allowsURL(url, URLLinkify) {
+			continue
+		}
+
+		urlText := text[ln.start:ln.end]
+		if unescape {
+			urlText = normalizeLinkText(urlText)
+		}
+
+		pos := ln.start
+
+		if pos > lastPos {
+			tok := Text{
+				Content: text[lastPos:pos],
+				Lvl:     level,
 			}
+			nodes = append(nodes, &tok)
+		}
+
+		nodes = append(nodes, &LinkOpen{
+			Href: md.rewriteURL(url, URLLinkify),
+			Auto: true,
+			Lvl:  level,
+		})
+		nodes = append(nodes, &Text{
+			Content: urlText,
+			Lvl:     level + 1,
+		})
+		nodes = append(nodes, &LinkClose{
+			Lvl: level,
+		})
+
+		lastPos = ln.end
+	}
+
+	if lastPos == 0 {
+		return nil
+	}
+
+	if lastPos < len(text) {
+		tok := Text{
+			Content: text[lastPos:],
+			Lvl:     level,
+		}
+		nodes = append(nodes, &tok)
+	}
+
+	return nodes
+}
+
+func ruleLinkify(s *stateCore) {
+	if !s.md.Linkify {
+		return
+	}
+
+	fn := linkifyText
+	if s.md.GFM {
+		fn = gfmLinkifyText
+	}
+
+	for _, tok := range s.tokens {
+		if tok, ok := tok.(*Inline); ok {
+			replaceUnlinkedText(tok, s.env, func(t *Text) []Token {
+				return fn(s.md, t)
+			})
 		}
 	}
 }
@@ -0,0 +1,92 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"net/url"
+	"strings"
+)
+
+// LinkPolicy sets the target, rel and class attributes of the links to
+// external sites, i.e. the links with a host other than those in Hosts and
+// the host of the base URL. An entry in Hosts also covers its subdomains.
+// Relative links, mailto: and tel: links and images are never external.
+// Empty Target, Rel and Class add no attribute.
+type LinkPolicy struct {
+	Hosts  []string // internal hosts, e.g. "example.com"
+	Target string   // target of external links
+	Rel    string   // rel of external links
+	Class  string   // class added to external links, e.g. for an icon
+}
+
+// DefaultLinkPolicy returns a policy that opens the links to hosts other
+// than the given ones in a new window, with rel="noopener noreferrer
+// nofollow ugc".
+func DefaultLinkPolicy(hosts ...string) *LinkPolicy {
+	return &LinkPolicy{
+		Hosts:  hosts,
+		Target: "_blank",
+		Rel:    "noopener noreferrer nofollow ugc",
+	}
+}
+
+func matchesHost(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+func (m *Markdown) isExternalLink(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if m.baseURL != nil && host == strings.ToLower(m.baseURL.Hostname()) {
+		return false
+	}
+	for _, h := range m.LinkPolicy.Hosts {
+		if matchesHost(host, h) {
+			return false
+		}
+	}
+	return true
+}
+
+func ruleLinkPolicy(s *stateCore) {
+	p := s.md.LinkPolicy
+	if p == nil {
+		return
+	}
+
+	for _, tok := range s.tokens {
+		tok, ok := tok.(*Inline)
+		if !ok {
+			continue
+		}
+		for _, itok := range tok.Children {
+			link, ok := itok.(*LinkOpen)
+			if !ok || !s.md.isExternalLink(link.Href) {
+				continue
+			}
+			link.Target = p.Target
+			link.Rel = p.Rel
+			if p.Class != "" {
+				if link.Class != "" {
+					link.Class += " "
+				}
+				link.Class += p.Class
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package markdown
+
+import "testing"
+
+func TestExternalLinks(t *testing.T) {
+	const ext = ` target="_blank" rel="noopener noreferrer nofollow ugc"`
+
+	runRenderTests(t, []renderTest{
+		{"[a](https://other.org/x)", "<p><a href=\"https://other.org/x\"" + ext + ">a</a></p>\n"},
+		{"[a](https://example.com/x) [b](https://docs.example.com) [c](/x)", "<p><a href=\"https://example.com/x\">a</a> <a href=\"https://docs.example.com\">b</a> <a href=\"/x\">c</a></p>\n"},
+		{"[a](https://notexample.com)", "<p><a href=\"https://notexample.com\"" + ext + ">a</a></p>\n"},
+		{"<https://other.org> other.org/x", "<p><a href=\"https://other.org\"" + ext + ">https://other.org</a> <a href=\"http://other.org/x\"" + ext + ">other.org/x</a></p>\n"},
+		{"[a][r]\n\n[r]: //other.org", "<p><a href=\"//other.org\"" + ext + ">a</a></p>\n"},
+		{"[a](HTTPS://OTHER.ORG) [b](https://EXAMPLE.com:8080/x)", "<p><a href=\"HTTPS://OTHER.ORG\"" + ext + ">a</a> <a href=\"https://EXAMPLE.com:8080/x\">b</a></p>\n"},
+		{"[a](https://example.com.evil.org) [b](https://example.com@evil.org)", "<p><a href=\"https://example.com.evil.org\"" + ext + ">a</a> <a href=\"https://example.com@evil.org\"" + ext + ">b</a></p>\n"},
+		{"<mailto:a@other.org> [t](tel:+123) ![i](https://other.org/i.png)", "<p><a href=\"mailto:a@other.org\">mailto:a@other.org</a> <a href=\"tel:+123\">t</a> <img src=\"https://other.org/i.png\" alt=\"i\"></p>\n"},
+	}, ExternalLinks(DefaultLinkPolicy("example.com")), Linkify(true))
+}
+
+func TestExternalLinksOptions(t *testing.T) {
+	p := &LinkPolicy{Rel: "noopener", Class: "external"}
+
+	runRenderTests(t, []renderTest{
+		{"[a](https://other.org)", "<p><a href=\"https://other.org\" class=\"external\" rel=\"noopener\">a</a></p>\n"},
+	}, ExternalLinks(p))
+
+	runRenderTests(t, []renderTest{
+		{"[a](https://other.org)", "<p><a href=\"https://other.org\" class=\"external\" rel=\"noopener nofollow\">a</a></p>\n"},
+	}, Nofollow(true), ExternalLinks(p))
+
+	runRenderTests(t, []renderTest{
+		{"[a](/x) [b](https://repo.org/y)", "<p><a href=\"https://repo.org/x\">a</a> <a href=\"https://repo.org/y\">b</a></p>\n"},
+	}, BaseURL("https://repo.org/docs/"), ExternalLinks(p))
+
+	runRenderTests(t, []renderTest{
+		{"[a](https://other.org)", "<p><a href=\"https://other.org\">a</a></p>\n"},
+	}, ExternalLinks(nil))
+}
@@ -40,7 +40,7 @@ func skipBulletListMarker(s *stateBlock, startLine int) int {
 	pos++
 	max := s.eMarks[startLine]
 
-	if pos < max && src[pos] != ' ' {
+	if pos < max && src[pos] != ' ' && src[pos] != '\t' {
 		return -1
 	}
 
@@ -62,6 +62,7 @@ func skipOrderedListMarker(s *stateBlock, startLine int) int {
 		return -1
 	}
 
+	start := pos
 	for {
 		if pos >= max {
 			return -1
@@ -71,6 +72,9 @@ func skipOrderedListMarker(s *stateBlock, startLine int) int {
 		pos++
 
 		if byteutil.IsDigit(b) {
+			if pos-start > 9 {
+				return -1
+			}
 			continue
 		}
 
@@ -81,7 +85,7 @@ func skipOrderedListMarker(s *stateBlock, startLine int) int {
 		return -1
 	}
 
-	if pos < max && src[pos] != ' ' {
+	if pos < max && src[pos] != ' ' && src[pos] != '\t' {
 		return -1
 	}
 
@@ -104,15 +108,32 @@ func markParagraphsTight(s *stateBlock, idx int) {
 }
 
 func ruleList(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
-	shift := s.tShift[startLine]
-	if shift < 0 {
+	shift := s.sCount[startLine]
+	if shift < 0 || shift-s.blkIndent > 3 {
 		return
 	}
 
+	// A list item cannot be indented by four or more spaces relative to
+	// the enclosing list.
+	if s.listIndent >= 0 && shift-s.listIndent > 3 && shift < s.blkIndent {
+		return
+	}
+
+	// Only a non-empty item of a bullet list or of an ordered list
+	// starting at 1 can interrupt a paragraph.
+	interrupting := silent && s.parentType == ptParagraph && shift >= s.blkIndent
+
+	src := s.src
 	isOrdered := false
+	markerValue := 0
 	posAfterMarker := skipOrderedListMarker(s, startLine)
 	if posAfterMarker > 0 {
 		isOrdered = true
+		start := s.bMarks[startLine] + s.tShift[startLine]
+		markerValue, _ = strconv.Atoi(src[start : posAfterMarker-1])
+		if interrupting && markerValue != 1 {
+			return
+		}
 	} else {
 		posAfterMarker = skipBulletListMarker(s, startLine)
 		if posAfterMarker < 0 {
@@ -120,7 +141,10 @@ func ruleList(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 		}
 	}
 
-	src := s.src
+	if interrupting && s.skipSpaces(posAfterMarker) >= s.eMarks[startLine] {
+		return
+	}
+
 	markerChar := src[posAfterMarker-1]
 
 	if silent {
@@ -131,9 +155,6 @@ func ruleList(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 
 	var listMap *[2]int
 	if isOrdered {
-		start := s.bMarks[startLine] + shift
-		markerValue, _ := strconv.Atoi(src[start : posAfterMarker-1])
-
 		tok := &OrderedListOpen{
 			Order: markerValue,
 			Map:   [2]int{startLine, 0},
@@ -154,21 +175,33 @@ func ruleList(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 	tight := true
 outer:
 	for nextLine < endLine {
-		contentStart := s.skipSpaces(posAfterMarker)
 		max := s.eMarks[nextLine]
+		initial := s.sCount[nextLine] + posAfterMarker - (s.bMarks[nextLine] + s.tShift[nextLine])
+		offset := initial
+
+		contentStart := posAfterMarker
+		for ; contentStart < max; contentStart++ {
+			if b := src[contentStart]; b == '\t' {
+				offset += 4 - (offset+s.bsCount[nextLine])%4
+			} else if b == ' ' {
+				offset++
+			} else {
+				break
+			}
+		}
 
 		var indentAfterMarker int
 		if contentStart >= max {
 			indentAfterMarker = 1
 		} else {
-			indentAfterMarker = contentStart - posAfterMarker
+			indentAfterMarker = offset - initial
 		}
 
 		if indentAfterMarker > 4 {
 			indentAfterMarker = 1
 		}
 
-		indent := posAfterMarker - s.bMarks[nextLine] + indentAfterMarker
+		indent := initial + indentAfterMarker
 
 		tok := &ListItemOpen{
 			Map: [2]int{startLine, 0},
@@ -177,15 +210,28 @@ outer:
 		itemMap := &tok.Map
 
 		oldIndent := s.blkIndent
+		oldListIndent := s.listIndent
 		oldTight := s.tight
 		oldTShift := s.tShift[startLine]
+		oldSCount := s.sCount[startLine]
 		oldParentType := s.parentType
 		s.tShift[startLine] = contentStart - s.bMarks[startLine]
+		s.sCount[startLine] = offset
+		s.listIndent = s.blkIndent
 		s.blkIndent = indent
 		s.tight = true
 		s.parentType = ptList
 
-		s.md.block.tokenize(s, startLine, endLine)
+		if contentStart >= max && s.isLineEmpty(startLine+1) {
+			// An item can begin with at most one blank line; after two
+			// the item is empty and the list ends.
+			s.line = startLine + 2
+			if s.line > endLine {
+				s.line = endLine
+			}
+		} else {
+			s.md.block.tokenize(s, startLine, endLine)
+		}
 
 		if !s.tight || prevEmptyEnd {
 			tight = false
@@ -199,7 +245,9 @@ outer:
 		}
 
 		s.blkIndent = oldIndent
+		s.listIndent = oldListIndent
 		s.tShift[startLine] = oldTShift
+		s.sCount[startLine] = oldSCount
 		s.tight = oldTight
 		s.parentType = oldParentType
 
@@ -208,7 +256,6 @@ outer:
 		startLine = s.line
 		nextLine = startLine
 		(*itemMap)[1] = nextLine
-		contentStart = s.bMarks[startLine]
 
 		if nextLine >= endLine {
 			break
@@ -218,7 +265,7 @@ outer:
 			break
 		}
 
-		if s.tShift[nextLine] < s.blkIndent {
+		if s.sCount[nextLine] < s.blkIndent || s.sCount[nextLine]-s.blkIndent > 3 {
 			break
 		}
 
@@ -16,7 +16,10 @@ package markdown
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"io/fs"
+	"net/url"
 )
 
 type Markdown struct {
@@ -31,19 +34,70 @@ type RenderOptions struct {
 	Breaks     bool   // convert \n in paragraphs into <br>
 	LangPrefix string // CSS language class prefix for fenced blocks
 	Nofollow   bool   // add rel="nofollow" to the links
+	Del        bool   // render strikethrough as <del> instead of <s>
+
+	// AlignOutput selects between the style, class and align attributes
+	// for the alignment of table cells. Only AlignStyle writes style
+	// attributes, which a strict Content-Security-Policy blocks.
+	AlignOutput      AlignOutput
+	AlignClassPrefix string // prefix of the alignment classes
+
+	Highlighter Highlighter // syntax highlighter for fenced blocks
+
+	// ContainerRenderers maps container names to custom renderers
+	// used instead of the default <div class="name"> markup.
+	ContainerRenderers map[string]ContainerRenderer
 }
 
+// ContainerRenderer writes the markup for a *ContainerOpen or a
+// *ContainerClose token.
+type ContainerRenderer func(w io.Writer, tok Token)
+
 type options struct {
-	HTML        bool    // allow raw HTML in the markup
-	Tables      bool    // GFM tables
-	Linkify     bool    // autoconvert URL-like text to links
-	Typographer bool    // enable some typographic replacements
-	Quotes      [4]rune // double/single quotes replacement pairs
-	MaxNesting  int     // maximum nesting level
+	HTML           bool    // allow raw HTML in the markup
+	GFM            bool    // GitHub Flavored Markdown extensions and rules
+	Tables         bool    // GFM tables
+	ExtendedTables bool    // captions, cell spans and multi-line rows in tables
+	GridTables     bool    // Pandoc-style +---+ grid tables
+	Containers     bool    // ::: fenced containers
+	Alerts         bool    // GitHub-style > [!NOTE] alerts
+	Figures        bool    // standalone titled images as <figure>
+	FigureLabel    string  // label of numbered figures; "" disables numbering
+	Linkify        bool    // autoconvert URL-like text to links
+	Typographer    bool    // enable some typographic replacements
+	Quotes         [4]rune // double/single quotes replacement pairs
+	CJKFriendly    bool    // CJK-aware softbreaks and emphasis
+	Hardened       bool    // bounded inline scanning for untrusted input
+	LowAlloc       bool    // slab-allocated tokens and pooled parser state
+	MaxNesting     int     // maximum nesting level
+	MaxBytes       int     // maximum input size for ParseContext; 0 means no limit
+	MaxLines       int     // maximum number of lines for ParseContext; 0 means no limit
+	MaxTokens      int     // maximum number of tokens for ParseContext; 0 means no limit
+
+	WikiLinks    WikiLinkResolver        // resolver for [[page]] links; nil disables them
+	RefResolvers map[RefKind]RefResolver // resolvers for @mentions, #issues and commits
+	IncludeFS    fs.FS                   // file system for !include; nil disables includes
+	Preset       TypographerPreset       // spacing, dashes and ellipsis of the typographer
+	Replacements []Replacement           // ordered typographic replacement rules
+	HTMLPolicy   *HTMLPolicy             // allowlist for raw HTML; nil leaves it as is
+	URLPolicy    *URLPolicy              // which URLs to link; nil means DefaultURLPolicy()
+	BaseURL      string                  // base URL for relative link and image URLs
+	RewriteURL   URLRewriter             // rewrites link and image URLs
+	LinkPolicy   *LinkPolicy             // attributes of external links; nil adds none
+	StreamRefs   RefPolicy               // reference definitions in ParseStream
+
+	replacer *replacer
+	baseURL  *url.URL
 }
 
 type environment struct {
 	References map[string]map[string]string
+
+	includes []string // stack of the files being included
+	limits   *limiter // nil unless parsing with ParseContext
+	figures  int      // number of the last numbered figure
+	arena    *arena   // nil unless in the LowAlloc mode
+	steps    int      // inline rule calls and bytes scanned ahead
 }
 
 type coreRule func(*stateCore)
@@ -51,17 +105,26 @@ type coreRule func(*stateCore)
 func New(opts ...option) *Markdown {
 	m := &Markdown{
 		options: options{
-			Tables:      true,
-			Linkify:     true,
-			Typographer: true,
-			Quotes:      [4]rune{'“', '”', '‘', '’'},
-			MaxNesting:  20,
+			Tables:       true,
+			Linkify:      true,
+			Typographer:  true,
+			Quotes:       defaultPreset.Quotes,
+			Preset:       defaultPreset,
+			Replacements: DefaultReplacements,
+			MaxNesting:   20,
+		},
+		renderOptions: RenderOptions{
+			LangPrefix:       "language-",
+			AlignClassPrefix: "align-",
 		},
-		renderOptions: RenderOptions{LangPrefix: "language-"},
 	}
 	for _, opt := range opts {
 		opt(m)
 	}
+	m.replacer = newReplacer(m.Replacements)
+	if m.BaseURL != "" {
+		m.baseURL, _ = url.Parse(m.BaseURL)
+	}
 	return m
 }
 
@@ -70,21 +133,73 @@ func (m *Markdown) Parse(src []byte) []Token {
 		return nil
 	}
 
+	return m.parse(src, &environment{})
+}
+
+// ParseContext is like Parse, but enforces the MaxBytes, MaxLines,
+// MaxTokens and MaxNesting limits and stops when ctx is done. In either
+// case it returns no tokens and a *LimitError or the context's error.
+func (m *Markdown) ParseContext(ctx context.Context, src []byte) ([]Token, error) {
+	if m.MaxBytes > 0 && len(src) > m.MaxBytes {
+		return nil, &LimitError{LimitBytes, m.MaxBytes}
+	}
+	if m.MaxLines > 0 && countLines(src) > m.MaxLines {
+		return nil, &LimitError{LimitLines, m.MaxLines}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	env := &environment{
+		limits: &limiter{ctx: ctx, maxTokens: m.MaxTokens},
+	}
+	tokens := m.parse(src, env)
+	if env.limits.err == nil {
+		env.limits.err = ctx.Err()
+	}
+	if env.limits.err != nil {
+		return nil, env.limits.err
+	}
+	return tokens, nil
+}
+
+func (m *Markdown) parse(src []byte, env *environment) []Token {
+	if m.LowAlloc && env.arena == nil {
+		env.arena = &arena{}
+	}
 	s := &stateCore{
 		md:  m,
-		env: &environment{},
+		env: env,
 	}
 	s.tokens = m.block.parse(src, m, s.env)
+	if s.env.limits != nil && s.env.limits.err != nil {
+		return nil
+	}
+
+	m.runCoreRules(s)
+	return s.tokens
+}
 
+func (m *Markdown) runCoreRules(s *stateCore) {
 	for _, r := range []coreRule{
 		ruleInline,
+		ruleCJKBreaks,
+		ruleTaskLists,
+		ruleTagFilter,
+		ruleSanitizeHTML,
+		ruleFigures,
 		ruleLinkify,
+		ruleRefLinks,
+		ruleLinkPolicy,
 		ruleReplacements,
 		ruleSmartQuotes,
+		rulePresetSpacing,
 	} {
 		r(s)
 	}
-	return s.tokens
 }
 
 func (m *Markdown) Render(w io.Writer, src []byte) error {
@@ -95,6 +210,16 @@ func (m *Markdown) Render(w io.Writer, src []byte) error {
 	return NewRenderer(w).Render(m.Parse(src), m.renderOptions)
 }
 
+// RenderContext is like Render, but parses src with ParseContext.
+func (m *Markdown) RenderContext(ctx context.Context, w io.Writer, src []byte) error {
+	tokens, err := m.ParseContext(ctx, src)
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	return NewRenderer(w).Render(tokens, m.renderOptions)
+}
+
 func (m *Markdown) RenderTokens(w io.Writer, tokens []Token) error {
 	if len(tokens) == 0 {
 		return nil
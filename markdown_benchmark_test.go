@@ -1,7 +1,6 @@
 package markdown
 
 import (
-	"io/ioutil"
 	"testing"
 
 	"github.com/russross/blackfriday"
@@ -9,12 +8,10 @@ import (
 
 func BenchmarkRenderSpecNoHTML(b *testing.B) {
 	b.StopTimer()
-	data, err := ioutil.ReadFile("spec/spec-0.20.txt")
-	if err != nil {
-		b.Fatal(err)
-	}
+	data := specCorpus()
 
 	md := New(HTML(false), XHTMLOutput(true))
+	b.ReportAllocs()
 	b.StartTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -24,12 +21,10 @@ func BenchmarkRenderSpecNoHTML(b *testing.B) {
 
 func BenchmarkRenderSpec(b *testing.B) {
 	b.StopTimer()
-	data, err := ioutil.ReadFile("spec/spec-0.20.txt")
-	if err != nil {
-		b.Fatal(err)
-	}
+	data := specCorpus()
 
 	md := New(HTML(true), XHTMLOutput(true))
+	b.ReportAllocs()
 	b.StartTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -37,13 +32,63 @@ func BenchmarkRenderSpec(b *testing.B) {
 	}
 }
 
-func BenchmarkRenderSpecBlackFriday(b *testing.B) {
+func BenchmarkRenderSpecLowAlloc(b *testing.B) {
+	b.StopTimer()
+	data := specCorpus()
+
+	md := New(HTML(true), XHTMLOutput(true), LowAlloc(true))
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		md.RenderToString(data)
+	}
+}
+
+func BenchmarkParseSpec(b *testing.B) {
+	b.StopTimer()
+	data := specCorpus()
+
+	md := New(HTML(true))
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		md.Parse(data)
+	}
+}
+
+func BenchmarkParseSpecLowAlloc(b *testing.B) {
+	b.StopTimer()
+	data := specCorpus()
+
+	md := New(HTML(true), LowAlloc(true))
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		md.Parse(data)
+	}
+}
+
+func BenchmarkDocumentReset(b *testing.B) {
 	b.StopTimer()
-	data, err := ioutil.ReadFile("spec/spec-0.20.txt")
-	if err != nil {
-		panic(err)
+	data := specCorpus()
+
+	d := New(HTML(true), LowAlloc(true)).ParseDocument(data)
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		d.Reset(data)
 	}
+}
+
+func BenchmarkRenderSpecBlackFriday(b *testing.B) {
+	b.StopTimer()
+	data := specCorpus()
 
+	b.ReportAllocs()
 	b.StartTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -2,9 +2,10 @@ package markdown
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/opennota/wd"
@@ -33,6 +34,16 @@ func loadExamplesFromJSON(fn string) []example {
 	return examples
 }
 
+// specCorpus returns the examples of the CommonMark spec as one document.
+func specCorpus() []byte {
+	var b strings.Builder
+	for _, ex := range loadExamplesFromJSON(*commonMarkSpec) {
+		b.WriteString(ex.Markdown)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
 func render(src string, options ...option) (_ string, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -43,25 +54,103 @@ func render(src string, options ...option) (_ string, err error) {
 	return md.RenderToString([]byte(src)), nil
 }
 
+// renderTest is a markdown input and the HTML it should render to.
+type renderTest struct {
+	in   string
+	want string
+}
+
+// runRenderTests renders the inputs with the options and reports the
+// ones that do not render as wanted.
+func runRenderTests(t *testing.T, tests []renderTest, options ...option) {
+	t.Helper()
+	for _, tt := range tests {
+		got, err := render(tt.in, options...)
+		if err != nil {
+			t.Errorf("render(%q): PANIC (%v)", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("render(%q):\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+var commonMarkSpec = flag.String("spec", "spec/commonmark-0.31.2.json", "CommonMark examples to test against")
+
+type sectionResult struct {
+	name   string
+	passed int
+	total  int
+}
+
 func TestCommonMark(t *testing.T) {
-	examples := loadExamplesFromJSON("spec/commonmark-0.20.json")
+	runSpecExamples(t, loadExamplesFromJSON(*commonMarkSpec),
+		HTML(true), XHTMLOutput(true), Linkify(false), Typographer(false), LangPrefix("language-"))
+}
+
+var gfmSpec = flag.String("gfmspec", "spec/gfm-0.29.json", "GitHub Flavored Markdown examples to test the GFM mode against")
+
+// gfmSections are the sections of the GFM spec with its extensions.
+var gfmSections = map[string]bool{
+	"Tables (extension)":              true,
+	"Strikethrough (extension)":       true,
+	"Task list items (extension)":     true,
+	"Autolinks (extension)":           true,
+	"Disallowed Raw HTML (extension)": true,
+}
+
+func TestGFMSpec(t *testing.T) {
+	if _, err := os.Stat(*gfmSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	var examples []example
+	for _, ex := range loadExamplesFromJSON(*gfmSpec) {
+		if gfmSections[ex.Section] {
+			examples = append(examples, ex)
+		}
+	}
+	if len(examples) == 0 {
+		t.Fatalf("%s has no examples of the GFM extensions", *gfmSpec)
+	}
+	// The extension examples are written in HTML, not XHTML.
+	runSpecExamples(t, examples, GFM(true), HTML(true), Typographer(false))
+}
+
+// runSpecExamples renders the examples of a spec, reports the ones that
+// do not render as in the spec and logs the pass rate of every section.
+func runSpecExamples(t *testing.T, examples []example, options ...option) {
+	t.Helper()
+
+	var sections []*sectionResult
 	for _, ex := range examples {
-		result, err := render(ex.Markdown, HTML(true), XHTMLOutput(true), Linkify(false), Typographer(false), LangPrefix("language-"))
+		if len(sections) == 0 || sections[len(sections)-1].name != ex.Section {
+			sections = append(sections, &sectionResult{name: ex.Section})
+		}
+		sec := sections[len(sections)-1]
+		sec.total++
+
+		result, err := render(ex.Markdown, options...)
 		if err != nil {
 			t.Errorf("#%d (%s): PANIC (%v)", ex.Num, ex.Section, err)
 		} else if result != ex.HTML {
 			d := wd.ColouredDiff(ex.HTML, result, false)
 			d = wd.NumberLines(d)
 			t.Errorf("#%d (%s):\n%s", ex.Num, ex.Section, d)
+		} else {
+			sec.passed++
 		}
 	}
+
+	passed := 0
+	for _, sec := range sections {
+		passed += sec.passed
+		t.Logf("%-40s %3d/%3d (%5.1f%%)", sec.name, sec.passed, sec.total, 100*float64(sec.passed)/float64(sec.total))
+	}
+	t.Logf("%-40s %3d/%3d (%5.1f%%)", "Total", passed, len(examples), 100*float64(passed)/float64(len(examples)))
 }
 
 func TestRenderSpec(t *testing.T) {
-	data, err := ioutil.ReadFile("spec/spec-0.20.txt")
-	if err != nil {
-		t.Fatal(err)
-	}
+	data := specCorpus()
 
 	md := New(HTML(true), XHTMLOutput(true))
 	md.RenderToString(data)
@@ -35,10 +35,10 @@ func ruleNewline(s *stateInline, silent bool) (_ bool) {
 				s.pushToken(&Hardbreak{})
 			} else {
 				s.pending.Truncate(n)
-				s.pushToken(&Softbreak{})
+				s.pushToken(s.env.arena.softbreak())
 			}
 		} else {
-			s.pushToken(&Softbreak{})
+			s.pushToken(s.env.arena.softbreak())
 		}
 	}
 
@@ -25,15 +25,23 @@ func init() {
 	}
 }
 
-func normalizeAndIndex(src []byte) (s string, bMarks []int, eMarks []int, tShift []int) {
-	buf := make([]byte, len(src)*4)
-	i := 0
+func normalizeAndIndex(src []byte) (s string, bMarks, eMarks, tShift, sCount []int) {
+	return normalizeAndIndexTo(src, nil, nil, nil, nil, nil)
+}
+
+// normalizeAndIndexTo is normalizeAndIndex using buf as the scratch
+// buffer if it is large enough, and appending the line marks to the
+// given slices. The tabs are kept; sCount has the indents in columns,
+// with the tab stops every 4 columns.
+func normalizeAndIndexTo(src, buf []byte, bMarks, eMarks, tShift, sCount []int) (string, []int, []int, []int, []int) {
+	if len(buf) < len(src)*4 {
+		buf = make([]byte, len(src)*4)
+	}
 	j := 0
 	pos := 0
 	skipNextLf := false
-	lineStart := 0
-	lastTabPos := 0
 	indent := 0
+	offset := 0
 	indentFound := false
 	start := 0
 
@@ -51,17 +59,21 @@ func normalizeAndIndex(src []byte) (s string, bMarks []int, eMarks []int, tShift
 		if !(r <= 0x20 && special[r]) {
 			j += utf8.EncodeRune(buf[j:], r)
 			indentFound = true
-			i++
 			continue
 		}
 
 		switch r {
-		case ' ':
-			buf[j] = ' '
+		case ' ', '\t':
+			buf[j] = byte(r)
 			j++
 
 			if !indentFound {
 				indent++
+				if r == '\t' {
+					offset += 4 - offset%4
+				} else {
+					offset++
+				}
 			}
 		case '\r':
 			skipNextLf = true
@@ -70,37 +82,27 @@ func normalizeAndIndex(src []byte) (s string, bMarks []int, eMarks []int, tShift
 			bMarks = append(bMarks, start)
 			eMarks = append(eMarks, j)
 			tShift = append(tShift, indent)
+			sCount = append(sCount, offset)
 			indentFound = false
 			indent = 0
+			offset = 0
 
 			buf[j] = '\n'
 			j++
-			lineStart = i + 1
-			lastTabPos = 0
 
 			start = j
-		case '\t':
-			k := (i - lineStart - lastTabPos) % 4
-			j += copy(buf[j:], "    "[k:])
-			lastTabPos = i - lineStart + 1
-
-			if !indentFound {
-				indent += 4 - k
-			}
 		case '\x00':
 			j += copy(buf[j:], runeErrorStr)
 			indentFound = true
 		}
-
-		i++
 	}
 
 	if j > 0 && buf[j-1] != '\n' {
 		bMarks = append(bMarks, start)
 		eMarks = append(eMarks, j)
 		tShift = append(tShift, indent)
+		sCount = append(sCount, offset)
 	}
 
-	s = string(buf[:j])
-	return
+	return string(buf[:j]), bMarks, eMarks, tShift, sCount
 }
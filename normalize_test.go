@@ -7,28 +7,28 @@ import (
 
 func TestNormalizeAndIndex(t *testing.T) {
 	type testCase struct {
-		in      string
-		out     string
-		b, e, s []int
+		in         string
+		out        string
+		b, e, s, c []int
 	}
 	testCases := []testCase{
-		{"abc", "abc", []int{0}, []int{3}, []int{0}},
-		{" abc", " abc", []int{0}, []int{4}, []int{1}},
-		{"    abc", "    abc", []int{0}, []int{7}, []int{4}},
-		{"abc\n", "abc\n", []int{0}, []int{3}, []int{0}},
-		{"abc\r", "abc\n", []int{0}, []int{3}, []int{0}},
-		{"abc\r\n", "abc\n", []int{0}, []int{3}, []int{0}},
-		{"abc\td", "abc d", []int{0}, []int{5}, []int{0}},
-		{"abc\x00def", "abc\ufffddef", []int{0}, []int{9}, []int{0}},
-		{"ab\tc", "ab  c", []int{0}, []int{5}, []int{0}},
-		{"a\tabc", "a   abc", []int{0}, []int{7}, []int{0}},
-		{"", "", nil, nil, nil},
-		{"\tabc", "    abc", []int{0}, []int{7}, []int{4}},
-		{"abc\n def\r\n\tghi\rj\tkl\x00\n", "abc\n def\n    ghi\nj   kl\ufffd\n", []int{0, 4, 9, 17}, []int{3, 8, 16, 26}, []int{0, 1, 4, 0}},
-		{"абв\n где\r\n\tёжз\rи\tйк\x00\n", "абв\n где\n    ёжз\nи   йк\ufffd\n", []int{0, 7, 15, 26}, []int{6, 14, 25, 38}, []int{0, 1, 4, 0}},
+		{"abc", "abc", []int{0}, []int{3}, []int{0}, []int{0}},
+		{" abc", " abc", []int{0}, []int{4}, []int{1}, []int{1}},
+		{"    abc", "    abc", []int{0}, []int{7}, []int{4}, []int{4}},
+		{"abc\n", "abc\n", []int{0}, []int{3}, []int{0}, []int{0}},
+		{"abc\r", "abc\n", []int{0}, []int{3}, []int{0}, []int{0}},
+		{"abc\r\n", "abc\n", []int{0}, []int{3}, []int{0}, []int{0}},
+		{"abc\td", "abc\td", []int{0}, []int{5}, []int{0}, []int{0}},
+		{"abc\x00def", "abc\ufffddef", []int{0}, []int{9}, []int{0}, []int{0}},
+		{"", "", nil, nil, nil, nil},
+		{"\tabc", "\tabc", []int{0}, []int{4}, []int{1}, []int{4}},
+		{"  \tabc", "  \tabc", []int{0}, []int{6}, []int{3}, []int{4}},
+		{"   \t\tabc", "   \t\tabc", []int{0}, []int{8}, []int{5}, []int{8}},
+		{"abc\n def\r\n\tghi\rj\tkl\x00\n", "abc\n def\n\tghi\nj\tkl\ufffd\n", []int{0, 4, 9, 14}, []int{3, 8, 13, 21}, []int{0, 1, 1, 0}, []int{0, 1, 4, 0}},
+		{"абв\n где\r\n\tёжз\rи\tйк\x00\n", "абв\n где\n\tёжз\nи\tйк\ufffd\n", []int{0, 7, 15, 23}, []int{6, 14, 22, 33}, []int{0, 1, 1, 0}, []int{0, 1, 4, 0}},
 	}
 	for _, tc := range testCases {
-		out, b, e, s := normalizeAndIndex([]byte(tc.in))
+		out, b, e, s, c := normalizeAndIndex([]byte(tc.in))
 		if out != tc.out {
 			t.Errorf("normalize(%q):\nstring = %q\n    want %q", tc.in, out, tc.out)
 		}
@@ -41,5 +41,8 @@ func TestNormalizeAndIndex(t *testing.T) {
 		if !reflect.DeepEqual(s, tc.s) {
 			t.Errorf("normalize(%q):\ntShift = %#v\n    want %#v", tc.in, s, tc.s)
 		}
+		if !reflect.DeepEqual(c, tc.c) {
+			t.Errorf("normalize(%q):\nsCount = %#v\n    want %#v", tc.in, c, tc.c)
+		}
 	}
 }
@@ -13,6 +13,8 @@
 
 package markdown
 
+import "io/fs"
+
 type option func(m *Markdown)
 
 func HTML(b bool) option {
@@ -41,6 +43,80 @@ func Quotes(s string) option {
 	}
 }
 
+// TypographerLocale selects the quotes, spacing, dashes and ellipsis of
+// the typographer preset registered for the locale ("en", "fr", "de", "ru"
+// or a preset added with RegisterTypographerPreset). Unknown locales are
+// ignored.
+func TypographerLocale(locale string) option {
+	return func(m *Markdown) {
+		if p, ok := lookupTypographerPreset(locale); ok {
+			m.Preset = p
+			m.Quotes = p.Quotes
+		}
+	}
+}
+
+// Replacements sets the ordered list of typographic replacement rules,
+// e.g. append([]Replacement{rule}, DefaultReplacements...).
+func Replacements(rules ...Replacement) option {
+	return func(m *Markdown) {
+		m.Replacements = rules
+	}
+}
+
+// AddReplacements appends the rules to the typographic replacement rules.
+func AddReplacements(rules ...Replacement) option {
+	return func(m *Markdown) {
+		m.Replacements = append(m.Replacements[:len(m.Replacements):len(m.Replacements)], rules...)
+	}
+}
+
+// DisableReplacements removes the named rules (e.g. "endash") from the
+// typographic replacement rules.
+func DisableReplacements(names ...string) option {
+	return func(m *Markdown) {
+		var rules []Replacement
+	outer:
+		for _, rule := range m.Replacements {
+			for _, name := range names {
+				if rule.Name == name {
+					continue outer
+				}
+			}
+			rules = append(rules, rule)
+		}
+		m.Replacements = rules
+	}
+}
+
+// CJKFriendly drops the softbreaks between two Chinese or Japanese
+// characters and lets emphasis delimiters next to CJK text open and close
+// around punctuation, as in **「强调」**文字.
+func CJKFriendly(b bool) option {
+	return func(m *Markdown) {
+		m.CJKFriendly = b
+	}
+}
+
+// Hardened makes the inline rules give up on the strikethrough, links, code
+// spans and autolinks that would take repeated scans of the rest of the
+// text, so that crafted input cannot make the parsing quadratic. Markup
+// nested deeper than MaxNesting may render differently.
+func Hardened(b bool) option {
+	return func(m *Markdown) {
+		m.Hardened = b
+	}
+}
+
+// LowAlloc allocates the most common tokens in slabs, one per parse or
+// per Document, and reuses the buffers of the parser state across
+// parses, to put less pressure on the garbage collector.
+func LowAlloc(b bool) option {
+	return func(m *Markdown) {
+		m.LowAlloc = b
+	}
+}
+
 func MaxNesting(n int) option {
 	return func(m *Markdown) {
 		m.MaxNesting = n
@@ -65,14 +141,194 @@ func LangPrefix(p string) option {
 	}
 }
 
+// AlignWith selects how the alignment of table cells is written: as a
+// style attribute (the default), as a class or as an align attribute.
+func AlignWith(out AlignOutput) option {
+	return func(m *Markdown) {
+		m.renderOptions.AlignOutput = out
+	}
+}
+
+// AlignClassPrefix sets the prefix of the classes written by
+// AlignWith(AlignClass); the default is "align-".
+func AlignClassPrefix(p string) option {
+	return func(m *Markdown) {
+		m.renderOptions.AlignClassPrefix = p
+	}
+}
+
+func Highlight(h Highlighter) option {
+	return func(m *Markdown) {
+		m.renderOptions.Highlighter = h
+	}
+}
+
 func Nofollow(b bool) option {
 	return func(m *Markdown) {
 		m.renderOptions.Nofollow = b
 	}
 }
 
+// GFM turns on the GitHub Flavored Markdown mode: tables, task lists,
+// single-tilde strikethrough, extended www and email autolinks and the
+// filtering of disallowed raw HTML tags. The mode renders strikethrough
+// as <del> and the alignment of table cells as align attributes, as
+// GitHub does; pass AlignWith after GFM to change the latter.
+func GFM(b bool) option {
+	return func(m *Markdown) {
+		m.GFM = b
+		m.renderOptions.Del = b
+		if b {
+			m.Tables = true
+			m.Linkify = true
+			m.renderOptions.AlignOutput = AlignAttr
+		}
+	}
+}
+
+// SanitizeHTML filters the raw HTML allowed by the HTML option through
+// the policy, e.g. DefaultHTMLPolicy().
+func SanitizeHTML(p *HTMLPolicy) option {
+	return func(m *Markdown) {
+		m.HTMLPolicy = p
+	}
+}
+
+// ValidateURLs sets the policy that decides which link and image URLs are
+// kept, in place of DefaultURLPolicy().
+func ValidateURLs(p *URLPolicy) option {
+	return func(m *Markdown) {
+		m.URLPolicy = p
+	}
+}
+
+// BaseURL resolves the relative link and image URLs against base, e.g.
+// "https://example.com/repo/blob/main/docs/". A base that does not parse
+// is ignored.
+func BaseURL(base string) option {
+	return func(m *Markdown) {
+		m.BaseURL = base
+	}
+}
+
+// RewriteURLs passes the URLs of links and images, once normalized,
+// validated and resolved against the base URL, through fn, e.g. to turn
+// .md links into .html ones or to prefix images with a CDN host.
+func RewriteURLs(fn URLRewriter) option {
+	return func(m *Markdown) {
+		m.RewriteURL = fn
+	}
+}
+
+// ExternalLinks sets the target, rel and class attributes of the links to
+// external sites according to the policy, e.g. DefaultLinkPolicy("example.com").
+func ExternalLinks(p *LinkPolicy) option {
+	return func(m *Markdown) {
+		m.LinkPolicy = p
+	}
+}
+
+// MaxBytes limits the size of the input accepted by ParseContext.
+func MaxBytes(n int) option {
+	return func(m *Markdown) {
+		m.MaxBytes = n
+	}
+}
+
+// MaxLines limits the number of lines of the input accepted by
+// ParseContext.
+func MaxLines(n int) option {
+	return func(m *Markdown) {
+		m.MaxLines = n
+	}
+}
+
+// MaxTokens limits the number of block and inline tokens produced by
+// ParseContext.
+func MaxTokens(n int) option {
+	return func(m *Markdown) {
+		m.MaxTokens = n
+	}
+}
+
+// StreamReferences sets how ParseStream and RenderStream handle the
+// reference definitions that come after their use.
+func StreamReferences(p RefPolicy) option {
+	return func(m *Markdown) {
+		m.StreamRefs = p
+	}
+}
+
 func Tables(b bool) option {
 	return func(m *Markdown) {
 		m.Tables = b
 	}
 }
+
+func ExtendedTables(b bool) option {
+	return func(m *Markdown) {
+		m.ExtendedTables = b
+	}
+}
+
+func GridTables(b bool) option {
+	return func(m *Markdown) {
+		m.GridTables = b
+	}
+}
+
+func Figures(b bool) option {
+	return func(m *Markdown) {
+		m.Figures = b
+	}
+}
+
+// NumberFigures numbers the figures, captioning them as "label N: caption"
+// and giving them the IDs fig-1, fig-2 and so on.
+func NumberFigures(label string) option {
+	return func(m *Markdown) {
+		m.FigureLabel = label
+	}
+}
+
+func Alerts(b bool) option {
+	return func(m *Markdown) {
+		m.Alerts = b
+	}
+}
+
+func Containers(b bool) option {
+	return func(m *Markdown) {
+		m.Containers = b
+	}
+}
+
+func RenderContainer(name string, fn ContainerRenderer) option {
+	return func(m *Markdown) {
+		if m.renderOptions.ContainerRenderers == nil {
+			m.renderOptions.ContainerRenderers = make(map[string]ContainerRenderer)
+		}
+		m.renderOptions.ContainerRenderers[name] = fn
+	}
+}
+
+func WikiLinks(resolve WikiLinkResolver) option {
+	return func(m *Markdown) {
+		m.WikiLinks = resolve
+	}
+}
+
+func RefLinks(kind RefKind, resolve RefResolver) option {
+	return func(m *Markdown) {
+		if m.RefResolvers == nil {
+			m.RefResolvers = make(map[RefKind]RefResolver)
+		}
+		m.RefResolvers[kind] = resolve
+	}
+}
+
+func Include(fsys fs.FS) option {
+	return func(m *Markdown) {
+		m.IncludeFS = fsys
+	}
+}
@@ -18,16 +18,20 @@ import "strings"
 func ruleParagraph(s *stateBlock, startLine, _ int, _ bool) bool {
 	nextLine := startLine + 1
 	endLine := s.lineMax
+	oldParentType := s.parentType
+	s.parentType = ptParagraph
 
 outer:
 	for ; nextLine < endLine && !s.isLineEmpty(nextLine); nextLine++ {
-		shift := s.tShift[nextLine]
+		shift := s.sCount[nextLine]
 		if shift < 0 || shift-s.blkIndent > 3 {
 			continue
 		}
 
 		for _, r := range []blockRule{
 			ruleFence,
+			ruleInclude,
+			ruleContainer,
 			ruleBlockQuote,
 			ruleHR,
 			ruleList,
@@ -40,21 +44,21 @@ outer:
 			}
 		}
 	}
+	s.parentType = oldParentType
 
 	content := strings.TrimSpace(s.lines(startLine, nextLine, s.blkIndent, false))
 
 	s.line = nextLine
 
-	s.pushOpeningToken(&ParagraphOpen{
-		Map: [2]int{startLine, s.line},
-	})
-	s.pushToken(&Inline{
-		Content: content,
-		Map:     [2]int{startLine, s.line},
-	})
-	s.pushClosingToken(&ParagraphClose{
-		Map: [2]int{startLine, s.line},
-	})
+	openTok, inlineTok, closeTok := s.env.arena.paragraph()
+	openTok.Map = [2]int{startLine, s.line}
+	inlineTok.Content = content
+	inlineTok.Map = openTok.Map
+	closeTok.Map = openTok.Map
+
+	s.pushOpeningToken(openTok)
+	s.pushToken(inlineTok)
+	s.pushClosingToken(closeTok)
 
 	return true
 }
@@ -18,21 +18,41 @@ type block struct {
 
 type blockRule func(*stateBlock, int, int, bool) bool
 
-func (b block) parse(src []byte, md *Markdown, env *environment) []Token {
-	str, bMarks, eMarks, tShift := normalizeAndIndex(src)
-	bMarks = append(bMarks, len(str))
-	eMarks = append(eMarks, len(str))
-	tShift = append(tShift, 0)
+func newStateBlock(src []byte, md *Markdown, env *environment) *stateBlock {
 	var s stateBlock
-	s.bMarks = bMarks
-	s.eMarks = eMarks
-	s.tShift = tShift
-	s.lineMax = len(bMarks) - 1
-	s.src = str
+	str, bMarks, eMarks, tShift, sCount := normalizeAndIndex(src)
+	s.index(str, bMarks, eMarks, tShift, sCount, nil)
 	s.md = md
 	s.env = env
+	return &s
+}
 
-	b.tokenize(&s, s.line, s.lineMax)
+// index sets the normalized source and the line marks of the state.
+func (s *stateBlock) index(str string, bMarks, eMarks, tShift, sCount, bsCount []int) {
+	s.bMarks = append(bMarks, len(str))
+	s.eMarks = append(eMarks, len(str))
+	s.tShift = append(tShift, 0)
+	s.sCount = append(sCount, 0)
+	for range s.bMarks {
+		bsCount = append(bsCount, 0)
+	}
+	s.bsCount = bsCount
+	s.lineMax = len(s.bMarks) - 1
+	s.listIndent = -1
+	s.src = str
+}
+
+func (b block) parse(src []byte, md *Markdown, env *environment) []Token {
+	var s *stateBlock
+	if md.LowAlloc {
+		bufs := blockPool.Get().(*blockBuffers)
+		defer blockPool.Put(bufs)
+		s = bufs.stateBlock(src, md, env)
+	} else {
+		s = newStateBlock(src, md, env)
+	}
+
+	b.tokenize(s, s.line, s.lineMax)
 
 	return s.tokens
 }
@@ -49,11 +69,17 @@ func (block) tokenize(s *stateBlock, startLine, endLine int) {
 			break
 		}
 
-		if s.tShift[line] < s.blkIndent {
+		if s.sCount[line] < s.blkIndent {
 			break
 		}
 
 		if s.level >= maxNesting {
+			s.env.nestingExceeded(maxNesting)
+			s.line = endLine
+			break
+		}
+
+		if s.env.stopped() {
 			s.line = endLine
 			break
 		}
@@ -61,6 +87,8 @@ func (block) tokenize(s *stateBlock, startLine, endLine int) {
 		for _, r := range []blockRule{
 			ruleCode,
 			ruleFence,
+			ruleInclude,
+			ruleContainer,
 			ruleBlockQuote,
 			ruleHR,
 			ruleList,
@@ -68,6 +96,7 @@ func (block) tokenize(s *stateBlock, startLine, endLine int) {
 			ruleHeading,
 			ruleLHeading,
 			ruleHTMLBlock,
+			ruleGridTable,
 			ruleTable,
 			ruleParagraph,
 		} {
@@ -87,10 +116,6 @@ func (block) tokenize(s *stateBlock, startLine, endLine int) {
 		if line < endLine && s.isLineEmpty(line) {
 			hasEmptyLines = true
 			line++
-
-			if line < endLine && s.parentType == ptList && s.isLineEmpty(line) {
-				break
-			}
 			s.line = line
 		}
 	}
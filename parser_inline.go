@@ -30,6 +30,7 @@ func init() {
 		ruleBackticks,
 		ruleStrikeThrough,
 		ruleEmphasis,
+		ruleWikiLink,
 		ruleLink,
 		ruleImage,
 		ruleAutolink,
@@ -42,6 +43,9 @@ func (i inline) parse(src string, md *Markdown, env *environment) []Token {
 	if src == "" {
 		return nil
 	}
+	if md.LowAlloc {
+		return i.parsePooled(src, md, env)
+	}
 
 	var s stateInline
 	s.src = src
@@ -61,11 +65,19 @@ func (inline) tokenize(s *stateInline) {
 	max := s.posMax
 	src := s.src
 	maxNesting := s.md.MaxNesting
+	bottom, tokStart, level := len(s.delimiters), len(s.tokens), s.level
 
 outer:
 	for s.pos < max {
-		if s.level < maxNesting {
+		if s.env.stopped() {
+			break
+		}
+
+		if s.level >= maxNesting {
+			s.env.nestingExceeded(maxNesting)
+		} else {
 			for _, rule := range inlineRules {
+				s.env.steps++
 				if rule(s, false) {
 					if s.pos >= max {
 						break outer
@@ -83,9 +95,12 @@ outer:
 	if s.pending.Len() > 0 {
 		s.pushPending()
 	}
+
+	s.processEmphasis(bottom, tokStart, level)
 }
 
 func (inline) skipToken(s *stateInline) {
+	s.env.steps++
 	pos := s.pos
 	if s.cache != nil {
 		if pos, ok := s.cache[pos]; ok {
@@ -98,6 +113,7 @@ func (inline) skipToken(s *stateInline) {
 
 	if s.level < s.md.MaxNesting {
 		for _, r := range inlineRules {
+			s.env.steps++
 			if r(s, true) {
 				s.cache[pos] = s.pos
 				return
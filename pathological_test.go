@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+type pathologicalCase struct {
+	name string
+	gen  func(n int) string
+}
+
+// Inputs that make a naive inline parser do repeated scans of the rest
+// of the text, after cmark's pathological_tests.py.
+var pathologicalCases = []pathologicalCase{
+	{"nested strong emph", func(n int) string {
+		return strings.Repeat("*a **a ", n) + "b" + strings.Repeat(" a** a*", n)
+	}},
+	{"emph closers with no openers", func(n int) string { return strings.Repeat("a_ ", n) }},
+	{"emph openers with no closers", func(n int) string { return strings.Repeat("_a ", n) }},
+	{"strong openers with one closer", func(n int) string { return strings.Repeat("**a ", n) + "a**" }},
+	{"mismatched openers and closers", func(n int) string { return strings.Repeat("*a_ ", n) }},
+	{"strikethrough openers with no closers", func(n int) string { return strings.Repeat("~~a ", n) }},
+	{"link closers with no openers", func(n int) string { return strings.Repeat("a]", n) }},
+	{"link openers with no closers", func(n int) string { return strings.Repeat("[a", n) }},
+	{"link openers and emph closers", func(n int) string { return strings.Repeat("[ a_", n) }},
+	{"nested brackets", func(n int) string {
+		return strings.Repeat("[", n) + "a" + strings.Repeat("]", n)
+	}},
+	{"unclosed links", func(n int) string { return strings.Repeat("[a](<b", n) }},
+	{"unclosed autolinks", func(n int) string { return strings.Repeat("<a", n) + ">" }},
+	{"unclosed html", func(n int) string { return strings.Repeat("<a b=", n) }},
+	{"backticks", func(n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			b.WriteString("e")
+			b.WriteString(strings.Repeat("`", i))
+		}
+		return b.String()
+	}},
+	{"unclosed backticks", func(n int) string { return strings.Repeat("``a` ", n) }},
+}
+
+func TestHardenedSpec(t *testing.T) {
+	for _, ex := range loadExamplesFromJSON(*commonMarkSpec) {
+		want, _ := render(ex.Markdown, HTML(true), XHTMLOutput(true), Linkify(false), Typographer(false))
+		got, err := render(ex.Markdown, HTML(true), XHTMLOutput(true), Linkify(false), Typographer(false), Hardened(true))
+		if err != nil {
+			t.Errorf("#%d (%s): PANIC (%v)", ex.Num, ex.Section, err)
+		} else if got != want {
+			t.Errorf("#%d (%s): hardened mode renders %q, want %q", ex.Num, ex.Section, got, want)
+		}
+	}
+}
+
+// inlineSteps returns the number of inline rule calls and bytes scanned
+// ahead while parsing src.
+func inlineSteps(md *Markdown, src string) int {
+	env := &environment{}
+	md.parse([]byte(src), env)
+	return env.steps
+}
+
+func TestPathologicalScaling(t *testing.T) {
+	md := New(Hardened(true), HTML(true))
+	for _, tc := range pathologicalCases {
+		n := 1000
+		if tc.name == "backticks" {
+			// The input grows as n².
+			n = 100
+		}
+		small, large := tc.gen(n), tc.gen(10*n)
+		smallSteps := inlineSteps(md, small)
+		largeSteps := inlineSteps(md, large)
+
+		// A linear parser does about as many steps per byte on either
+		// input; allow for twice that on the larger one.
+		if largeSteps*len(small) > 2*smallSteps*len(large) {
+			t.Errorf("%s: %d steps for %d bytes, %d steps for %d bytes", tc.name, smallSteps, len(small), largeSteps, len(large))
+		}
+	}
+}
+
+func BenchmarkPathological(b *testing.B) {
+	for _, hardened := range []bool{false, true} {
+		md := New(Hardened(hardened), HTML(true))
+		for _, tc := range pathologicalCases {
+			name := tc.name
+			if hardened {
+				name += " (hardened)"
+			}
+			src := []byte(tc.gen(1000))
+			b.Run(name, func(b *testing.B) {
+				b.SetBytes(int64(len(src)))
+				for i := 0; i < b.N; i++ {
+					md.RenderToString(src)
+				}
+			})
+		}
+	}
+}
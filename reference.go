@@ -43,12 +43,13 @@ func ruleReference(s *stateBlock, startLine, _ int, silent bool) (_ bool) {
 	endLine := s.lineMax
 outer:
 	for ; nextLine < endLine && !s.isLineEmpty(nextLine); nextLine++ {
-		if s.tShift[nextLine]-s.blkIndent > 3 {
+		if s.sCount[nextLine]-s.blkIndent > 3 {
 			continue
 		}
 
 		for _, r := range []blockRule{
 			ruleFence,
+			ruleContainer,
 			ruleBlockQuote,
 			ruleHR,
 			ruleList,
@@ -91,7 +92,7 @@ outer:
 		b := str[pos]
 		if b == '\n' {
 			lines++
-		} else if b != ' ' {
+		} else if b != ' ' && b != '\t' {
 			break
 		}
 	}
@@ -101,7 +102,7 @@ outer:
 		return
 	}
 	href = normalizeLink(href)
-	if !validateLink(href) {
+	if !s.md.allowsURL(href, URLReference) {
 		return
 	}
 	pos = endpos
@@ -114,24 +115,37 @@ outer:
 		b := str[pos]
 		if b == '\n' {
 			lines++
-		} else if b != ' ' {
+		} else if b != ' ' && b != '\t' {
 			break
 		}
 	}
 
 	title, nlines, endpos, ok := parseLinkTitle(str, pos, max)
-	if pos < max && start != pos && ok {
+	hasTitle := pos < max && start != pos && ok
+	if hasTitle {
 		pos = endpos
 		lines += nlines
 	} else {
+		title = ""
 		pos = savedPos
 		lines = savedLineNo
 	}
 
-	for pos < max && str[pos] == ' ' {
+	for pos < max && (str[pos] == ' ' || str[pos] == '\t') {
 		pos++
 	}
 
+	if pos < max && str[pos] != '\n' && hasTitle {
+		// Garbage after the title, but the definition may still end
+		// at the destination.
+		title = ""
+		pos = savedPos
+		lines = savedLineNo
+		for pos < max && (str[pos] == ' ' || str[pos] == '\t') {
+			pos++
+		}
+	}
+
 	if pos < max && str[pos] != '\n' {
 		return
 	}
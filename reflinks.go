@@ -0,0 +1,245 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"strconv"
+
+	"github.com/opennota/byteutil"
+)
+
+type RefKind byte
+
+const (
+	RefMention RefKind = iota + 1 // @user
+	RefIssue                      // #123 or org/repo#123
+	RefCommit                     // 7 to 40 lowercase hex digits
+)
+
+// Ref describes a GitHub-style reference found in the text.
+type Ref struct {
+	Kind   RefKind
+	Text   string // the matched text
+	User   string // user name of a mention
+	Repo   string // org/repo of a cross-repository issue
+	Number int    // issue number
+	SHA    string // commit hash
+}
+
+// RefResolver returns the URL a reference links to, or "" to leave the
+// reference as plain text.
+type RefResolver func(ref Ref) (href string)
+
+type refMatch struct {
+	start int
+	end   int
+	ref   Ref
+}
+
+func isRefWordByte(b byte) bool {
+	return b >= 0x80 || b == '_' || byteutil.IsLetter(b) || byteutil.IsDigit(b)
+}
+
+func isHexDigit(b byte) bool {
+	return byteutil.IsDigit(b) || b >= 'a' && b <= 'f'
+}
+
+func refBoundaryAfter(s string, pos int) bool {
+	return pos >= len(s) || !(isRefWordByte(s[pos]) || s[pos] == '@' || s[pos] == '/')
+}
+
+func scanDigits(s string, pos int) int {
+	for pos < len(s) && byteutil.IsDigit(s[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func matchMention(s string, pos int) (user string, end int) {
+	start := pos
+	for pos < len(s) && pos-start < 39 && (byteutil.IsLetter(s[pos]) || byteutil.IsDigit(s[pos]) || s[pos] == '-') {
+		if s[pos] == '-' && (pos == start || s[pos-1] == '-') {
+			return "", 0
+		}
+		pos++
+	}
+	if pos == start || s[pos-1] == '-' || !refBoundaryAfter(s, pos) {
+		return "", 0
+	}
+	return s[start:pos], pos
+}
+
+func matchIssue(s string, pos int) (number, end int) {
+	start := pos
+	pos = scanDigits(s, pos)
+	if pos == start || !refBoundaryAfter(s, pos) {
+		return 0, 0
+	}
+	number, err := strconv.Atoi(s[start:pos])
+	if err != nil {
+		return 0, 0
+	}
+	return number, pos
+}
+
+func matchRepoIssue(s string, pos int) (repo string, number, end int) {
+	start := pos
+	for pos < len(s) && (byteutil.IsLetter(s[pos]) || byteutil.IsDigit(s[pos]) || s[pos] == '-') {
+		pos++
+	}
+	if pos == start || pos >= len(s) || s[pos] != '/' {
+		return "", 0, 0
+	}
+	pos++
+	nameStart := pos
+	for pos < len(s) && (byteutil.IsLetter(s[pos]) || byteutil.IsDigit(s[pos]) || s[pos] == '-' || s[pos] == '_' || s[pos] == '.') {
+		pos++
+	}
+	if pos == nameStart || pos >= len(s) || s[pos] != '#' {
+		return "", 0, 0
+	}
+	number, end = matchIssue(s, pos+1)
+	if end == 0 {
+		return "", 0, 0
+	}
+	return s[start:pos], number, end
+}
+
+func matchCommit(s string, pos int) (sha string, end int) {
+	start := pos
+	digits := 0
+	for pos < len(s) && isHexDigit(s[pos]) {
+		if byteutil.IsDigit(s[pos]) {
+			digits++
+		}
+		pos++
+	}
+	if n := pos - start; n < 7 || n > 40 || digits == 0 || !refBoundaryAfter(s, pos) {
+		return "", 0
+	}
+	return s[start:pos], pos
+}
+
+func findRefs(s string) (matches []refMatch) {
+	pos := 0
+	for pos < len(s) {
+		if pos > 0 && isRefWordByte(s[pos-1]) {
+			pos++
+			continue
+		}
+
+		var m refMatch
+		switch b := s[pos]; {
+		case b == '@':
+			if user, end := matchMention(s, pos+1); end > 0 {
+				m = refMatch{pos, end, Ref{Kind: RefMention, User: user}}
+			}
+		case b == '#':
+			if number, end := matchIssue(s, pos+1); end > 0 {
+				m = refMatch{pos, end, Ref{Kind: RefIssue, Number: number}}
+			}
+		case isRefWordByte(b):
+			if repo, number, end := matchRepoIssue(s, pos); end > 0 {
+				m = refMatch{pos, end, Ref{Kind: RefIssue, Repo: repo, Number: number}}
+			} else if sha, end := matchCommit(s, pos); end > 0 {
+				m = refMatch{pos, end, Ref{Kind: RefCommit, SHA: sha}}
+			}
+		}
+
+		if m.end == 0 {
+			pos++
+			continue
+		}
+
+		m.ref.Text = s[m.start:m.end]
+		matches = append(matches, m)
+		pos = m.end
+	}
+
+	return
+}
+
+func ruleRefLinks(s *stateCore) {
+	resolvers := s.md.RefResolvers
+	if len(resolvers) == 0 {
+		return
+	}
+
+	refLinkText := func(currentTok *Text) []Token {
+		text := currentTok.Content
+		matches := findRefs(text)
+		if len(matches) == 0 {
+			return nil
+		}
+
+		var nodes []Token
+		level := currentTok.Lvl
+		lastPos := 0
+
+		for _, m := range matches {
+			resolve := resolvers[m.ref.Kind]
+			if resolve == nil {
+				continue
+			}
+			href := resolve(m.ref)
+			if href == "" {
+				continue
+			}
+			href = normalizeLink(href)
+			if !s.md.allowsURL(href, URLLink) {
+				continue
+			}
+
+			if m.start > lastPos {
+				nodes = append(nodes, &Text{
+					Content: text[lastPos:m.start],
+					Lvl:     level,
+				})
+			}
+
+			nodes = append(nodes, &LinkOpen{
+				Href: s.md.rewriteURL(href, URLLink),
+				Lvl:  level,
+			})
+			nodes = append(nodes, &Text{
+				Content: m.ref.Text,
+				Lvl:     level + 1,
+			})
+			nodes = append(nodes, &LinkClose{
+				Lvl: level,
+			})
+
+			lastPos = m.end
+		}
+
+		if lastPos == 0 {
+			return nil
+		}
+
+		if lastPos < len(text) {
+			nodes = append(nodes, &Text{
+				Content: text[lastPos:],
+				Lvl:     level,
+			})
+		}
+
+		return nodes
+	}
+
+	for _, tok := range s.tokens {
+		if tok, ok := tok.(*Inline); ok {
+			replaceUnlinkedText(tok, s.env, refLinkText)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package markdown
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestFindRefs(t *testing.T) {
+	type testCase struct {
+		in   string
+		want []Ref
+	}
+	testCases := []testCase{
+		{"", nil},
+		{"@alice", []Ref{{Kind: RefMention, Text: "@alice", User: "alice"}}},
+		{"cc @bob-x, @carol.", []Ref{
+			{Kind: RefMention, Text: "@bob-x", User: "bob-x"},
+			{Kind: RefMention, Text: "@carol", User: "carol"},
+		}},
+		{"mail me@example.com", nil},
+		{"@-bad @bad- @a--b @org/team", nil},
+		{"fixes #12 and #3x", []Ref{{Kind: RefIssue, Text: "#12", Number: 12}}},
+		{"C#1", nil},
+		{"see org/repo.js#45!", []Ref{{Kind: RefIssue, Text: "org/repo.js#45", Repo: "org/repo.js", Number: 45}}},
+		{"in a1b2c3d.", []Ref{{Kind: RefCommit, Text: "a1b2c3d", SHA: "a1b2c3d"}}},
+		{"deadbeef a1b2c3 a1b2c3dx A1B2C3D4", nil},
+	}
+	for _, tc := range testCases {
+		var got []Ref
+		for _, m := range findRefs(tc.in) {
+			if tc.in[m.start:m.end] != m.ref.Text {
+				t.Errorf("findRefs(%q): bad offsets %d:%d for %q", tc.in, m.start, m.end, m.ref.Text)
+			}
+			got = append(got, m.ref)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("findRefs(%q):\n got %#v\nwant %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRefLinks(t *testing.T) {
+	mention := func(ref Ref) string { return "/" + ref.User }
+	issue := func(ref Ref) string {
+		if ref.Repo != "" {
+			return ""
+		}
+		return "/issues/" + strconv.Itoa(ref.Number)
+	}
+
+	runRenderTests(t, []renderTest{
+		{"hi @alice", "<p>hi <a href=\"/alice\">@alice</a></p>\n"},
+		{"#1, org/repo#2", "<p><a href=\"/issues/1\">#1</a>, org/repo#2</p>\n"},
+		{"`@alice` [@alice](/x)", "<p><code>@alice</code> <a href=\"/x\">@alice</a></p>\n"},
+		{"<a href=\"/y\">@alice</a>", "<p><a href=\"/y\">@alice</a></p>\n"},
+		{"a1b2c3d", "<p>a1b2c3d</p>\n"},
+		{"@alice's **@bob**", "<p><a href=\"/alice\">@alice</a>'s <strong><a href=\"/bob\">@bob</a></strong></p>\n"},
+		{"@alice\n@bob", "<p><a href=\"/alice\">@alice</a>\n<a href=\"/bob\">@bob</a></p>\n"},
+		{"mail a@alice", "<p>mail a@alice</p>\n"},
+	}, HTML(true), RefLinks(RefMention, mention), RefLinks(RefIssue, issue))
+}
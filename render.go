@@ -14,6 +14,7 @@
 package markdown
 
 import (
+	"bytes"
 	"io"
 	"strconv"
 	"strings"
@@ -25,6 +26,10 @@ type Renderer struct {
 	w *monadicWriter
 }
 
+// textEscaper escapes the text content of an element. Unlike attribute
+// values, the text needs no escaping of quotes.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
 func NewRenderer(w io.Writer) *Renderer {
 	return &Renderer{newMonadicWriter(w)}
 }
@@ -59,15 +64,136 @@ func (r *Renderer) renderInlineAsText(tokens []Token) {
 	}
 }
 
+// nextToken returns the token following tokens[idx], skipping the include
+// markers, which produce no output.
+func nextToken(tokens []Token, idx int) Token {
+	for idx++; idx < len(tokens); idx++ {
+		switch tokens[idx].(type) {
+		case *IncludeOpen, *IncludeClose:
+			continue
+		}
+		return tokens[idx]
+	}
+	return nil
+}
+
+func (r *Renderer) renderFence(tok *Fence, options RenderOptions) {
+	var code string
+	highlighted := false
+	if options.Highlighter != nil {
+		if s, ok := options.Highlighter.Highlight(tok.Lang, tok.Content); ok {
+			if strings.HasPrefix(s, "<pre") {
+				r.w.WriteString(s)
+				return
+			}
+			code, highlighted = s, true
+		}
+	}
+
+	// Only a string value is a title; title=true says nothing to show.
+	title, _ := tok.Attrs["title"].(string)
+	if title != "" {
+		r.w.WriteString(`<figure class="code-block"><figcaption>`)
+		textEscaper.WriteString(r.w, title)
+		r.w.WriteString("</figcaption>")
+	}
+
+	r.w.WriteString("<pre><code")
+	if tok.Lang != "" {
+		r.w.WriteString(` class="`)
+		r.w.WriteString(options.LangPrefix)
+		html.WriteEscapedString(r.w, tok.Lang)
+		r.w.WriteByte('"')
+	}
+	r.w.WriteByte('>')
+
+	linenos := tok.Attrs.Bool("linenos")
+	hlLines := tok.Attrs.Ranges("hl_lines")
+	if linenos || hlLines != nil {
+		if !highlighted {
+			var buf bytes.Buffer
+			textEscaper.WriteString(&buf, tok.Content)
+			code = buf.String()
+		}
+		start, ok := tok.Attrs.Int("linenostart")
+		if !ok {
+			start = 1
+		}
+		for i, line := range splitHTMLLines(code) {
+			if inLineRanges(hlLines, i+1) {
+				r.w.WriteString(`<span class="line hl">`)
+			} else {
+				r.w.WriteString(`<span class="line">`)
+			}
+			if linenos {
+				r.w.WriteString(`<span class="ln">`)
+				r.w.WriteString(strconv.Itoa(start + i))
+				r.w.WriteString("</span>")
+			}
+			r.w.WriteString(line)
+			r.w.WriteString("</span>\n")
+		}
+	} else if highlighted {
+		r.w.WriteString(code)
+	} else {
+		textEscaper.WriteString(r.w, tok.Content)
+	}
+
+	r.w.WriteString("</code></pre>")
+	if title != "" {
+		r.w.WriteString("</figure>")
+	}
+}
+
+func (r *Renderer) renderCellAttrs(align Align, colspan, rowspan int, options RenderOptions) {
+	if colspan > 1 {
+		r.w.WriteString(` colspan="`)
+		r.w.WriteString(strconv.Itoa(colspan))
+		r.w.WriteByte('"')
+	}
+	if rowspan > 1 {
+		r.w.WriteString(` rowspan="`)
+		r.w.WriteString(strconv.Itoa(rowspan))
+		r.w.WriteByte('"')
+	}
+	if align != AlignNone {
+		switch options.AlignOutput {
+		case AlignClass:
+			r.w.WriteString(` class="`)
+			html.WriteEscapedString(r.w, options.AlignClassPrefix)
+		case AlignAttr:
+			r.w.WriteString(` align="`)
+		default:
+			r.w.WriteString(` style="text-align:`)
+		}
+		r.w.WriteString(align.String())
+		r.w.WriteByte('"')
+	}
+	r.w.WriteByte('>')
+}
+
 func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 	tok := tokens[idx]
 
 	switch tok := tok.(type) {
 	case *BlockquoteClose:
-		r.w.WriteString("</blockquote>")
+		if tok.Alert != AlertNone {
+			r.w.WriteString("</div>")
+		} else {
+			r.w.WriteString("</blockquote>")
+		}
 
 	case *BlockquoteOpen:
-		r.w.WriteString("<blockquote>")
+		if alert := tok.Alert; alert != AlertNone {
+			r.w.WriteString(`<div class="markdown-alert markdown-alert-`)
+			r.w.WriteString(alert.String())
+			r.w.WriteString(`">`)
+			r.w.WriteString("\n<p class=\"markdown-alert-title\">")
+			r.w.WriteString(alert.Title())
+			r.w.WriteString("</p>")
+		} else {
+			r.w.WriteString("<blockquote>")
+		}
 
 	case *BulletListClose:
 		r.w.WriteString("</ul>")
@@ -75,16 +201,45 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 	case *BulletListOpen:
 		r.w.WriteString("<ul>")
 
+	case *CaptionClose:
+		r.w.WriteString("</caption>")
+
+	case *CaptionOpen:
+		r.w.WriteString("<caption>")
+
 	case *CodeBlock:
 		r.w.WriteString("<pre><code>")
-		html.WriteEscapedString(r.w, tok.Content)
+		textEscaper.WriteString(r.w, tok.Content)
 		r.w.WriteString("</code></pre>")
 
 	case *CodeInline:
 		r.w.WriteString("<code>")
-		html.WriteEscapedString(r.w, tok.Content)
+		textEscaper.WriteString(r.w, tok.Content)
 		r.w.WriteString("</code>")
 
+	case *ContainerClose:
+		if fn, ok := options.ContainerRenderers[tok.Name]; ok {
+			fn(r.w, tok)
+		} else {
+			r.w.WriteString("</div>")
+		}
+
+	case *ContainerOpen:
+		if fn, ok := options.ContainerRenderers[tok.Name]; ok {
+			fn(r.w, tok)
+			break
+		}
+		r.w.WriteString(`<div class="`)
+		html.WriteEscapedString(r.w, tok.Name)
+		r.w.WriteString(`">`)
+		if tok.Title != "" {
+			r.w.WriteString("\n<p class=\"")
+			html.WriteEscapedString(r.w, tok.Name)
+			r.w.WriteString(`-title">`)
+			textEscaper.WriteString(r.w, tok.Title)
+			r.w.WriteString("</p>")
+		}
+
 	case *EmphasisClose:
 		r.w.WriteString("</em>")
 
@@ -92,17 +247,7 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 		r.w.WriteString("<em>")
 
 	case *Fence:
-		r.w.WriteString("<pre><code")
-		if tok.Params != "" {
-			langName := unescapeAll(strings.SplitN(tok.Params, " ", 2)[0])
-			r.w.WriteString(` class="`)
-			r.w.WriteString(options.LangPrefix)
-			html.WriteEscapedString(r.w, langName)
-			r.w.WriteByte('"')
-		}
-		r.w.WriteByte('>')
-		html.WriteEscapedString(r.w, tok.Content)
-		r.w.WriteString("</code></pre>")
+		r.renderFence(tok, options)
 
 	case *Hardbreak:
 		if options.XHTML {
@@ -111,6 +256,26 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 			r.w.WriteString("<br>\n")
 		}
 
+	case *FigureClose:
+		r.w.WriteString("<figcaption>")
+		if tok.Number > 0 {
+			textEscaper.WriteString(r.w, tok.Label)
+			r.w.WriteByte(' ')
+			r.w.WriteString(strconv.Itoa(tok.Number))
+			r.w.WriteString(": ")
+		}
+		textEscaper.WriteString(r.w, html.ReplaceEntities(tok.Caption))
+		r.w.WriteString("</figcaption></figure>")
+
+	case *FigureOpen:
+		if tok.ID != "" {
+			r.w.WriteString(`<figure id="`)
+			html.WriteEscapedString(r.w, tok.ID)
+			r.w.WriteString(`">`)
+		} else {
+			r.w.WriteString("<figure>")
+		}
+
 	case *HeadingClose:
 		r.w.WriteString("</h")
 		r.w.WriteByte("0123456789"[tok.HLevel])
@@ -135,6 +300,14 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 	case *HTMLInline:
 		r.w.WriteString(tok.Content)
 
+	case *IncludeOpen, *IncludeClose:
+		return // no output
+
+	case *IncludeError:
+		r.w.WriteString(`<p class="include-error">`)
+		textEscaper.WriteString(r.w, tok.Directive)
+		r.w.WriteString("</p>")
+
 	case *Image:
 		r.w.WriteString(`<img src="`)
 		html.WriteEscapedString(r.w, tok.Src)
@@ -170,8 +343,19 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 			html.WriteEscapedString(r.w, tok.Target)
 			r.w.WriteByte('"')
 		}
-		if options.Nofollow {
-			r.w.WriteString(` rel="nofollow"`)
+		if tok.Class != "" {
+			r.w.WriteString(` class="`)
+			html.WriteEscapedString(r.w, tok.Class)
+			r.w.WriteByte('"')
+		}
+		rel := tok.Rel
+		if options.Nofollow && !strings.Contains(" "+rel+" ", " nofollow ") {
+			rel = strings.TrimSpace(rel + " nofollow")
+		}
+		if rel != "" {
+			r.w.WriteString(` rel="`)
+			html.WriteEscapedString(r.w, rel)
+			r.w.WriteByte('"')
 		}
 		r.w.WriteByte('>')
 
@@ -185,7 +369,7 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 		r.w.WriteString("</ol>")
 
 	case *OrderedListOpen:
-		if tok.Order > 1 {
+		if tok.Order != 1 {
 			r.w.WriteString(`<ol start="`)
 			r.w.WriteString(strconv.Itoa(tok.Order))
 			r.w.WriteString(`">`)
@@ -196,7 +380,7 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 	case *ParagraphClose:
 		if !tok.Tight {
 			r.w.WriteString("</p>")
-		} else if tokens[idx+1].Closing() {
+		} else if next := nextToken(tokens, idx); next != nil && next.Closing() {
 			return // no newline
 		}
 
@@ -224,10 +408,18 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 		r.w.WriteString("<strong>")
 
 	case *StrikethroughClose:
-		r.w.WriteString("</s>")
+		if options.Del {
+			r.w.WriteString("</del>")
+		} else {
+			r.w.WriteString("</s>")
+		}
 
 	case *StrikethroughOpen:
-		r.w.WriteString("<s>")
+		if options.Del {
+			r.w.WriteString("<del>")
+		} else {
+			r.w.WriteString("<s>")
+		}
 
 	case *TableClose:
 		r.w.WriteString("</table>")
@@ -245,16 +437,23 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 		r.w.WriteString("</td>")
 
 	case *TdOpen:
-		if tok.Align != AlignNone {
-			r.w.WriteString(`<td style="text-align:`)
-			r.w.WriteString(tok.Align.String())
-			r.w.WriteString(`">`)
+		r.w.WriteString("<td")
+		r.renderCellAttrs(tok.Align, tok.Colspan, tok.Rowspan, options)
+
+	case *TaskCheckbox:
+		if tok.Checked {
+			r.w.WriteString(`<input checked="" disabled="" type="checkbox"`)
 		} else {
-			r.w.WriteString("<td>")
+			r.w.WriteString(`<input disabled="" type="checkbox"`)
+		}
+		if options.XHTML {
+			r.w.WriteString(" />")
+		} else {
+			r.w.WriteByte('>')
 		}
 
 	case *Text:
-		html.WriteEscapedString(r.w, tok.Content)
+		textEscaper.WriteString(r.w, tok.Content)
 
 	case *TheadClose:
 		r.w.WriteString("</thead>")
@@ -266,13 +465,8 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 		r.w.WriteString("</th>")
 
 	case *ThOpen:
-		if align := tok.Align; align != AlignNone {
-			r.w.WriteString(`<th style="text-align:`)
-			r.w.WriteString(align.String())
-			r.w.WriteString(`">`)
-		} else {
-			r.w.WriteString("<th>")
-		}
+		r.w.WriteString("<th")
+		r.renderCellAttrs(tok.Align, tok.Colspan, tok.Rowspan, options)
 
 	case *TrClose:
 		r.w.WriteString("</tr>")
@@ -289,7 +483,7 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 		needLf = true
 
 		if tok.Opening() {
-			nextTok := tokens[idx+1]
+			nextTok := nextToken(tokens, idx)
 			switch nextTok := nextTok.(type) {
 			case *Inline:
 				needLf = false
@@ -303,7 +497,10 @@ func (r *Renderer) renderToken(tokens []Token, idx int, options RenderOptions) {
 				}
 			}
 			if needLf && nextTok.Closing() && nextTok.Tag() == tok.Tag() {
-				needLf = false
+				// An empty blockquote is still written on two lines.
+				if _, ok := tok.(*BlockquoteOpen); !ok {
+					needLf = false
+				}
 			}
 		}
 	}
@@ -15,170 +15,254 @@ package markdown
 
 import (
 	"bytes"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/opennota/byteutil"
 )
 
-var (
-	trg       [256]bool
-	sym       [256]bool
-	exclquest [256]bool
-)
+// Replacement is a typographic replacement rule. A rule replaces either
+// the Literal text or the matches of the Regexp with With, in which $1 and
+// ${name} refer to the submatches of the Regexp. Rules are tried in order
+// at each position of the text, and the first one that matches wins.
+type Replacement struct {
+	Name         string
+	Literal      string
+	Regexp       *regexp.Regexp
+	With         string
+	WordBoundary bool // match only if not preceded or followed by a letter, digit or _
+
+	trigger string // the first bytes of the built-in rule's matches
+	match   func(s string, pos int, p *TypographerPreset) (with string, end int)
+}
+
+// DefaultReplacements are the built-in rules of the typographer.
+var DefaultReplacements = []Replacement{
+	{Name: "copyright", trigger: "(", match: matchSymbol("(c)", "©")},
+	{Name: "registered", trigger: "(", match: matchSymbol("(r)", "®")},
+	{Name: "trademark", trigger: "(", match: matchSymbol("(tm)", "™")},
+	{Name: "paragraph", trigger: "(", match: matchSymbol("(p)", "§")},
+	{Name: "plusminus", Literal: "+-", With: "±"},
+	{Name: "ellipsis", trigger: ".", match: matchEllipsis},
+	{Name: "questions", trigger: "?!", match: matchQuestions},
+	{Name: "commas", trigger: ",", match: matchCommas},
+	{Name: "endash", trigger: "-", match: matchDashes(2)},
+	{Name: "emdash", trigger: "-", match: matchDashes(3)},
+}
+
+func matchSymbol(sym, with string) func(string, int, *TypographerPreset) (string, int) {
+	return func(s string, pos int, _ *TypographerPreset) (string, int) {
+		if len(s)-pos < len(sym) {
+			return "", 0
+		}
+		for i := 0; i < len(sym); i++ {
+			if byteutil.ByteToLower(s[pos+i]) != sym[i] {
+				return "", 0
+			}
+		}
+		return with, pos + len(sym)
+	}
+}
 
-func init() {
-	for _, b := range "(!+,-.?" {
-		trg[b] = true
+func runEnd(s string, pos int, b byte) int {
+	for pos < len(s) && s[pos] == b {
+		pos++
 	}
-	for _, b := range "cprtCPRT" {
-		sym[b] = true
+	return pos
+}
+
+func matchEllipsis(s string, pos int, p *TypographerPreset) (string, int) {
+	end := runEnd(s, pos, '.')
+	if end-pos < 2 {
+		return "", 0
 	}
-	for _, b := range "?!" {
-		exclquest[b] = true
+	if pos > 0 && (s[pos-1] == '?' || s[pos-1] == '!') {
+		return "..", end
 	}
+	return p.Ellipsis, end
 }
 
-func performReplacements(s string) string {
-	var buf bytes.Buffer
+func isExclQuest(b byte) bool {
+	return b == '?' || b == '!'
+}
 
-	for i := 0; i < len(s); i++ {
-		b := s[i]
+func matchQuestions(s string, pos int, _ *TypographerPreset) (string, int) {
+	end := pos
+	for end < len(s) && isExclQuest(s[end]) {
+		end++
+	}
+	if end-pos < 4 {
+		return "", 0
+	}
+	return s[pos : pos+3], end
+}
 
-		if trg[b] {
+func matchCommas(s string, pos int, _ *TypographerPreset) (string, int) {
+	end := runEnd(s, pos, ',')
+	if end-pos < 2 {
+		return "", 0
+	}
+	return ",", end
+}
 
-		outer:
-			switch b {
-			case '(':
-				if i+2 >= len(s) {
-					break
-				}
+// matchDashes matches a run of exactly n dashes. Longer runs are left
+// alone.
+func matchDashes(n int) func(string, int, *TypographerPreset) (string, int) {
+	return func(s string, pos int, p *TypographerPreset) (string, int) {
+		if pos > 0 && s[pos-1] == '-' {
+			return "", 0
+		}
+		end := runEnd(s, pos, '-')
+		if end-pos != n {
+			return "", 0
+		}
+		if n == 2 {
+			return p.EnDash, end
+		}
+		return p.EmDash, end
+	}
+}
 
-				b2 := s[i+1]
-				if !sym[b2] {
-					break
-				}
+func isWordRuneBefore(s string, pos int) bool {
+	if pos == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:pos])
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
 
-				b2 = byteutil.ByteToLower(b2)
-				switch b2 {
-				case 'c', 'r', 'p':
-					if s[i+2] != ')' {
-						break outer
-					}
-					switch b2 {
-					case 'c':
-						buf.WriteString("©")
-					case 'r':
-						buf.WriteString("®")
-					case 'p':
-						buf.WriteString("§")
-					}
-					i += 2
-					continue
+func isWordRuneAt(s string, pos int) bool {
+	if pos >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[pos:])
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
 
-				case 't':
-					if i+3 >= len(s) {
-						break outer
-					}
-					if s[i+3] != ')' || byteutil.ByteToLower(s[i+2]) != 'm' {
-						break outer
-					}
-					buf.WriteString("™")
-					i += 3
-					continue
-				}
+type replacer struct {
+	rules    []Replacement
+	triggers [256]bool
+	regexps  bool
+}
 
-			case '+':
-				if i+1 >= len(s) || s[i+1] != '-' {
-					break
-				}
-				buf.WriteString("±")
-				i++
-				continue
+func newReplacer(rules []Replacement) *replacer {
+	r := &replacer{rules: rules}
+	for _, rule := range rules {
+		switch {
+		case rule.match != nil:
+			for i := 0; i < len(rule.trigger); i++ {
+				r.triggers[rule.trigger[i]] = true
+			}
+		case rule.Regexp != nil:
+			r.regexps = true
+		case rule.Literal != "":
+			r.triggers[rule.Literal[0]] = true
+		}
+	}
+	return r
+}
 
-			case '.':
-				if i+1 >= len(s) || s[i+1] != '.' {
-					break
-				}
+var defaultReplacer = newReplacer(DefaultReplacements)
 
-				j := i + 2
-				for j < len(s) && s[j] == '.' {
-					j++
-				}
-				if i == 0 || !(s[i-1] == '?' || s[i-1] == '!') {
-					buf.WriteString("…")
-				} else {
-					buf.WriteString("..")
-				}
-				i = j - 1
+func (r *replacer) replace(s string, p *TypographerPreset) string {
+	// The submatch indices of the regexp rules, by rule index.
+	var matches map[int][][]int
+	if r.regexps {
+		for i, rule := range r.rules {
+			if rule.Regexp == nil {
 				continue
-
-			case '?', '!':
-				if i+3 >= len(s) {
-					break
-				}
-				if !(exclquest[s[i+1]] && exclquest[s[i+2]] && exclquest[s[i+3]]) {
-					break
-				}
-				buf.WriteString(s[i : i+3])
-				j := i + 3
-				for j < len(s) && exclquest[s[j]] {
-					j++
+			}
+			if m := rule.Regexp.FindAllStringSubmatchIndex(s, -1); m != nil {
+				if matches == nil {
+					matches = make(map[int][][]int)
 				}
-				i = j - 1
-				continue
+				matches[i] = m
+			}
+		}
+	}
 
-			case ',':
-				if i+1 >= len(s) || s[i+1] != ',' {
-					break
-				}
-				buf.WriteByte(',')
-				j := i + 2
-				for j < len(s) && s[j] == ',' {
-					j++
-				}
-				i = j - 1
-				continue
+	var buf bytes.Buffer
+	last := 0
+	for pos := 0; pos < len(s); pos++ {
+		if !r.triggers[s[pos]] && matches == nil {
+			continue
+		}
 
-			case '-':
-				if i+1 >= len(s) || s[i+1] != '-' {
-					break
+		for i := range r.rules {
+			rule := &r.rules[i]
+			var with string
+			end := 0
+			switch {
+			case rule.match != nil:
+				if strings.IndexByte(rule.trigger, s[pos]) >= 0 {
+					with, end = rule.match(s, pos, p)
 				}
-				if i+2 >= len(s) || s[i+2] != '-' {
-					buf.WriteString("–")
-					i++
-					continue
+			case rule.Regexp != nil:
+				m := matches[i]
+				for len(m) > 0 && m[0][0] < pos {
+					m = m[1:]
 				}
-				if i+3 >= len(s) || s[i+3] != '-' {
-					buf.WriteString("—")
-					i += 2
-					continue
+				matches[i] = m
+				if len(m) > 0 && m[0][0] == pos && m[0][1] > pos {
+					with = string(rule.Regexp.ExpandString(nil, rule.With, s, m[0]))
+					end = m[0][1]
 				}
-
-				j := i + 3
-				for j < len(s) && s[j] == '-' {
-					j++
+			case rule.Literal != "":
+				if strings.HasPrefix(s[pos:], rule.Literal) {
+					with, end = rule.With, pos+len(rule.Literal)
 				}
-				buf.WriteString(s[i:j])
-				i = j - 1
+			}
+			if end == 0 {
 				continue
 			}
+			if rule.WordBoundary && (isWordRuneBefore(s, pos) || isWordRuneAt(s, end)) {
+				continue
+			}
+
+			buf.WriteString(s[last:pos])
+			buf.WriteString(with)
+			last = end
+			pos = end - 1
+			break
 		}
+	}
 
-		buf.WriteByte(b)
+	if last == 0 {
+		return s
 	}
+	buf.WriteString(s[last:])
 	return buf.String()
 }
 
+func performReplacements(s string) string {
+	return defaultReplacer.replace(s, &defaultPreset)
+}
+
 func ruleReplacements(s *stateCore) {
 	if !s.md.Typographer {
 		return
 	}
 
+	r := s.md.replacer
+	if r == nil {
+		r = defaultReplacer
+	}
+	p := &s.md.Preset
 	for _, tok := range s.tokens {
 		if tok, ok := tok.(*Inline); ok {
+			inAutolink := false
 			for _, itok := range tok.Children {
 				switch itok := itok.(type) {
+				case *LinkOpen:
+					inAutolink = itok.Auto
+				case *LinkClose:
+					inAutolink = false
 				case *Text:
-					itok.Content = performReplacements(itok.Content)
+					if !inAutolink {
+						itok.Content = r.replace(itok.Content, p)
+					}
 				}
 			}
 		}
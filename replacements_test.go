@@ -1,6 +1,9 @@
 package markdown
 
-import "testing"
+import (
+	"regexp"
+	"testing"
+)
 
 func TestPerformReplacements(t *testing.T) {
 	type testCase struct {
@@ -76,3 +79,33 @@ func TestPerformReplacements(t *testing.T) {
 		}
 	}
 }
+
+func TestReplacementRules(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"use --flag -- or not", "<p>use –flag – or not</p>\n"},
+		{"<http://a--b.com> http://c--d.com", "<p><a href=\"http://a--b.com\">http://a--b.com</a> <a href=\"http://c--d.com\">http://c--d.com</a></p>\n"},
+		{"`a -- b` [c -- d](e--f)", "<p><code>a -- b</code> <a href=\"e--f\">c – d</a></p>\n"},
+		{"    a -- b\n", "<pre><code>a -- b\n</code></pre>\n"},
+	})
+
+	runRenderTests(t, []renderTest{
+		{"use --flag -- or not", "<p>use --flag -- or not</p>\n"},
+		{"(c) ...", "<p>© …</p>\n"},
+	}, DisableReplacements("endash"))
+
+	runRenderTests(t, []renderTest{
+		{"use --flag -- or not", "<p>use --flag – or not</p>\n"},
+		{"--", "<p>–</p>\n"},
+		{"a--", "<p>a--</p>\n"},
+	}, Replacements(Replacement{Name: "endash", Literal: "--", With: "–", WordBoundary: true}))
+
+	runRenderTests(t, []renderTest{
+		{"(c) 1/2 and 11/2", "<p>© ½ and 11/2</p>\n"},
+		{"a 1/2x", "<p>a 1/2x</p>\n"},
+	}, AddReplacements(Replacement{Name: "half", Literal: "1/2", With: "½", WordBoundary: true}))
+
+	runRenderTests(t, []renderTest{
+		{"see 10x20 and 3x4", "<p>see 10×20 and 3×4</p>\n"},
+		{"a10x20", "<p>a10x20</p>\n"},
+	}, AddReplacements(Replacement{Name: "times", Regexp: regexp.MustCompile(`(\d+)x(\d+)`), With: "$1×$2", WordBoundary: true}))
+}
@@ -0,0 +1,49 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "net/url"
+
+// URLRewriter returns the URL to use in place of url, which comes from the
+// given context.
+type URLRewriter func(url string, ctx URLContext) string
+
+// resolveURL resolves the relative reference href against base. Absolute
+// and protocol-relative URLs, fragment-only links and the hrefs that do not
+// parse are left as is.
+func resolveURL(base *url.URL, href string) string {
+	if href[0] == '#' {
+		return href
+	}
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return href
+	}
+	return base.ResolveReference(u).String()
+}
+
+// rewriteURL resolves the normalized and validated url against the base
+// URL, if any, and passes the result through the rewrite callback.
+func (m *Markdown) rewriteURL(url string, ctx URLContext) string {
+	if url == "" {
+		return url
+	}
+	if m.baseURL != nil {
+		url = resolveURL(m.baseURL, url)
+	}
+	if m.RewriteURL != nil {
+		url = m.RewriteURL(url, ctx)
+	}
+	return url
+}
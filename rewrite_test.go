@@ -0,0 +1,44 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBaseURL(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"[a](setup.md)", "<p><a href=\"https://x.org/repo/docs/setup.md\">a</a></p>\n"},
+		{"![a](img/a.png)", "<p><img src=\"https://x.org/repo/docs/img/a.png\" alt=\"a\"></p>\n"},
+		{"[a](../README.md) [b](/root.md)", "<p><a href=\"https://x.org/repo/README.md\">a</a> <a href=\"https://x.org/root.md\">b</a></p>\n"},
+		{"[a][r]\n\n[r]: setup.md", "<p><a href=\"https://x.org/repo/docs/setup.md\">a</a></p>\n"},
+		{"[a](?q=1) [b](<a b.md>) [c](setup.md#sec) [d]()", "<p><a href=\"https://x.org/repo/docs/?q=1\">a</a> <a href=\"https://x.org/repo/docs/a%20b.md\">b</a> <a href=\"https://x.org/repo/docs/setup.md#sec\">c</a> <a href=\"\">d</a></p>\n"},
+		{"[a](#intro) [b](https://y.org/) [c](//z.org/p) <mailto:a@b.org>", "<p><a href=\"#intro\">a</a> <a href=\"https://y.org/\">b</a> <a href=\"//z.org/p\">c</a> <a href=\"mailto:a@b.org\">mailto:a@b.org</a></p>\n"},
+	}, BaseURL("https://x.org/repo/docs/"))
+
+	runRenderTests(t, []renderTest{
+		{"[a](setup.md)", "<p><a href=\"setup.md\">a</a></p>\n"},
+	}, BaseURL("::bad"))
+}
+
+func TestRewriteURLs(t *testing.T) {
+	rewrite := func(url string, ctx URLContext) string {
+		switch ctx {
+		case URLImage:
+			return "https://cdn.x.org" + url + "?v=2"
+		case URLLink:
+			if strings.HasSuffix(url, ".md") {
+				return strings.TrimSuffix(url, ".md") + ".html"
+			}
+		case URLAutolink, URLLinkify:
+			return url + "#ext"
+		}
+		return url
+	}
+
+	runRenderTests(t, []renderTest{
+		{"[a](/setup.md) ![b](/a.png)", "<p><a href=\"/setup.html\">a</a> <img src=\"https://cdn.x.org/a.png?v=2\" alt=\"b\"></p>\n"},
+		{"[a][r] ![b][r]\n\n[r]: /a.md", "<p><a href=\"/a.html\">a</a> <img src=\"https://cdn.x.org/a.md?v=2\" alt=\"b\"></p>\n"},
+		{"<https://x.org> https://y.org", "<p><a href=\"https://x.org#ext\">https://x.org</a> <a href=\"https://y.org#ext\">https://y.org</a></p>\n"},
+		{"[a](javascript:x)", "<p>[a](javascript:x)</p>\n"},
+	}, RewriteURLs(rewrite), Linkify(true))
+}
@@ -0,0 +1,462 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opennota/byteutil"
+	"github.com/opennota/html"
+)
+
+// HTMLPolicy is an allowlist of the tags and attributes that may appear in
+// raw HTML. Other tags are removed (along with their content in the case
+// of script, style and the like), other attributes are dropped, and so are
+// the style and on* event handler attributes whatever the policy says. The
+// attributes that hold URLs, such as href, src, action or srcset, must
+// pass the URL policy as URLHTML.
+type HTMLPolicy struct {
+	Tags        map[string][]string // allowed tags and their allowed attributes
+	GlobalAttrs []string            // attributes allowed on every allowed tag
+}
+
+// DefaultHTMLPolicy returns a policy that allows the common formatting
+// tags such as <details>, <kbd>, <sup> and <img>, but no scripts, styles,
+// forms or frames.
+func DefaultHTMLPolicy() *HTMLPolicy {
+	p := &HTMLPolicy{
+		Tags: map[string][]string{
+			"a":          {"href", "title", "name"},
+			"abbr":       {"title"},
+			"bdo":        {"dir"},
+			"blockquote": {"cite"},
+			"col":        {"span"},
+			"colgroup":   {"span"},
+			"del":        {"cite", "datetime"},
+			"details":    {"open"},
+			"img":        {"src", "alt", "title", "width", "height"},
+			"ins":        {"cite", "datetime"},
+			"ol":         {"start", "type", "reversed"},
+			"q":          {"cite"},
+			"td":         {"colspan", "rowspan", "align"},
+			"th":         {"colspan", "rowspan", "align", "scope"},
+			"time":       {"datetime"},
+		},
+		GlobalAttrs: []string{"title", "lang", "dir"},
+	}
+	for _, tag := range strings.Fields(`b bdi br caption cite code dd dfn div
+		dl dt em figcaption figure h1 h2 h3 h4 h5 h6 hr i kbd li mark p pre
+		rp rt ruby s samp small span strike strong sub summary sup table
+		tbody tfoot thead tr tt u ul var wbr`) {
+		p.Tags[tag] = nil
+	}
+	return p
+}
+
+func (p *HTMLPolicy) allowsAttr(tag, attr string) bool {
+	if attr == "style" || strings.HasPrefix(attr, "on") {
+		return false
+	}
+	for _, a := range p.GlobalAttrs {
+		if a == attr {
+			return true
+		}
+	}
+	for _, a := range p.Tags[tag] {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	voidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true,
+		"hr": true, "img": true, "input": true, "link": true, "meta": true,
+		"param": true, "source": true, "track": true, "wbr": true,
+	}
+
+	// The content of these elements is dropped along with the tags.
+	rawTextElements = map[string]bool{
+		"script": true, "style": true, "iframe": true, "object": true,
+		"embed": true, "noscript": true, "template": true, "textarea": true,
+		"title": true, "xmp": true, "noembed": true, "noframes": true,
+		"plaintext": true, "svg": true, "math": true,
+	}
+
+	urlAttrs = map[string]bool{
+		"href": true, "src": true, "cite": true, "action": true,
+		"formaction": true, "poster": true, "background": true,
+		"srcset": true, "xlink:href": true,
+	}
+)
+
+type htmlAttr struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+type htmlTag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attrs       []htmlAttr
+}
+
+func isTagNameByte(b byte) bool {
+	return byteutil.IsLetter(b) || byteutil.IsDigit(b) || b == '-'
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// parseHTMLTag parses the tag at the beginning of s and returns its length,
+// or 0 if s does not start with a tag.
+func parseHTMLTag(s string) (tag htmlTag, n int) {
+	pos := 1
+	if pos < len(s) && s[pos] == '/' {
+		tag.closing = true
+		pos++
+	}
+	start := pos
+	for pos < len(s) && isTagNameByte(s[pos]) {
+		pos++
+	}
+	if pos == start || !byteutil.IsLetter(s[start]) {
+		return tag, 0
+	}
+	tag.name = strings.ToLower(s[start:pos])
+
+	for {
+		for pos < len(s) && isHTMLSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) {
+			return tag, 0
+		}
+		switch s[pos] {
+		case '>':
+			return tag, pos + 1
+		case '/':
+			if pos+1 < len(s) && s[pos+1] == '>' {
+				tag.selfClosing = true
+				return tag, pos + 2
+			}
+			pos++
+			continue
+		}
+
+		start := pos
+		for pos < len(s) && !isHTMLSpace(s[pos]) && s[pos] != '=' && s[pos] != '>' && s[pos] != '/' {
+			pos++
+		}
+		attr := htmlAttr{name: strings.ToLower(s[start:pos])}
+		for pos < len(s) && isHTMLSpace(s[pos]) {
+			pos++
+		}
+		if pos < len(s) && s[pos] == '=' {
+			pos++
+			for pos < len(s) && isHTMLSpace(s[pos]) {
+				pos++
+			}
+			if pos >= len(s) {
+				return tag, 0
+			}
+			attr.hasValue = true
+			if q := s[pos]; q == '"' || q == '\'' {
+				end := strings.IndexByte(s[pos+1:], q)
+				if end < 0 {
+					return tag, 0
+				}
+				attr.value = s[pos+1 : pos+1+end]
+				pos += end + 2
+			} else {
+				start := pos
+				for pos < len(s) && !isHTMLSpace(s[pos]) && s[pos] != '>' {
+					pos++
+				}
+				attr.value = s[start:pos]
+			}
+		}
+		tag.attrs = append(tag.attrs, attr)
+	}
+}
+
+type htmlSanitizer struct {
+	policy   *HTMLPolicy
+	validate func(string, URLContext) bool
+	stack    []string // open elements
+	skip     string   // the raw text element whose content is being dropped
+}
+
+// skipRawText drops the content of the raw text element being skipped up to
+// and including its closing tag, and returns the position after it.
+func (z *htmlSanitizer) skipRawText(s string, pos int) int {
+	end := strings.Index(strings.ToLower(s[pos:]), "</"+z.skip)
+	if end < 0 {
+		return len(s)
+	}
+	z.skip = ""
+	pos += end
+	if gt := strings.IndexByte(s[pos:], '>'); gt >= 0 {
+		return pos + gt + 1
+	}
+	return len(s)
+}
+
+// unescapeAttr replaces the character references in an attribute value
+// the way browsers do, including the numeric ones without a semicolon.
+func unescapeAttr(s string) string {
+	if strings.IndexByte(s, '&') < 0 {
+		return s
+	}
+	var buf strings.Builder
+	for pos := 0; pos < len(s); {
+		i := strings.IndexByte(s[pos:], '&')
+		if i < 0 {
+			buf.WriteString(s[pos:])
+			break
+		}
+		buf.WriteString(s[pos : pos+i])
+		pos += i
+
+		if r, n := parseNumericRef(s[pos:]); n > 0 {
+			buf.WriteRune(r)
+			pos += n
+		} else if e, n := html.ParseEntity(s[pos:]); n > 0 {
+			buf.WriteString(e)
+			pos += n
+		} else {
+			buf.WriteByte('&')
+			pos++
+		}
+	}
+	return buf.String()
+}
+
+// parseNumericRef parses the numeric character reference at the
+// beginning of s, whose semicolon is optional, and returns its length.
+func parseNumericRef(s string) (r rune, n int) {
+	if len(s) < 3 || s[1] != '#' {
+		return 0, 0
+	}
+	pos, base, isDigit := 2, 10, byteutil.IsDigit
+	if s[pos] == 'x' || s[pos] == 'X' {
+		pos, base = 3, 16
+		isDigit = func(b byte) bool {
+			return byteutil.IsDigit(b) || b|0x20 >= 'a' && b|0x20 <= 'f'
+		}
+	}
+	start := pos
+	for pos < len(s) && isDigit(s[pos]) {
+		pos++
+	}
+	if pos == start {
+		return 0, 0
+	}
+	v, err := strconv.ParseUint(s[start:pos], base, 32)
+	if pos < len(s) && s[pos] == ';' {
+		pos++
+	}
+	if err != nil || v == 0 || !utf8.ValidRune(rune(v)) {
+		return utf8.RuneError, pos
+	}
+	return rune(v), pos
+}
+
+// allowsURLAttr reports whether the URLs of the attribute value pass the
+// URL policy. A srcset value is a comma-separated list of URLs, each
+// followed by an optional descriptor.
+func (z *htmlSanitizer) allowsURLAttr(name, value string) bool {
+	if name != "srcset" {
+		return z.validate(value, URLHTML)
+	}
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && !z.validate(fields[0], URLHTML) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTag writes the tag with the attributes allowed by the policy. The
+// values are decoded once, checked, and written escaped again, so that
+// what was checked is what a browser sees.
+func (z *htmlSanitizer) writeTag(buf *strings.Builder, tag htmlTag) {
+	buf.WriteByte('<')
+	buf.WriteString(tag.name)
+	for _, attr := range tag.attrs {
+		if !z.policy.allowsAttr(tag.name, attr.name) {
+			continue
+		}
+		value := unescapeAttr(attr.value)
+		if urlAttrs[attr.name] && !z.allowsURLAttr(attr.name, value) {
+			continue
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(attr.name)
+		if attr.hasValue {
+			buf.WriteString(`="`)
+			html.WriteEscapedString(buf, value)
+			buf.WriteByte('"')
+		}
+	}
+	if tag.selfClosing {
+		buf.WriteString(" /")
+	}
+	buf.WriteByte('>')
+}
+
+func (z *htmlSanitizer) closeTag(buf *strings.Builder, name string) {
+	for i := len(z.stack) - 1; i >= 0; i-- {
+		if z.stack[i] != name {
+			continue
+		}
+		for j := len(z.stack) - 1; j >= i; j-- {
+			buf.WriteString("</")
+			buf.WriteString(z.stack[j])
+			buf.WriteByte('>')
+		}
+		z.stack = z.stack[:i]
+		return
+	}
+}
+
+// sanitize filters the raw HTML s. The open elements are remembered
+// between the calls, so that the closing tags that do not match any of
+// them are dropped.
+func (z *htmlSanitizer) sanitize(s string) string {
+	var buf strings.Builder
+	pos := 0
+	for pos < len(s) {
+		if z.skip != "" {
+			pos = z.skipRawText(s, pos)
+			continue
+		}
+
+		i := strings.IndexByte(s[pos:], '<')
+		if i < 0 {
+			buf.WriteString(s[pos:])
+			break
+		}
+		buf.WriteString(s[pos : pos+i])
+		pos += i
+		rest := s[pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return buf.String()
+			}
+			pos += end + 7
+			continue
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return buf.String()
+			}
+			pos += end + 1
+			continue
+		}
+
+		tag, n := parseHTMLTag(rest)
+		if n == 0 {
+			buf.WriteString("&lt;")
+			pos++
+			continue
+		}
+		pos += n
+
+		if _, ok := z.policy.Tags[tag.name]; !ok {
+			if !tag.closing && !tag.selfClosing && rawTextElements[tag.name] {
+				z.skip = tag.name
+			}
+			continue
+		}
+
+		switch {
+		case tag.closing:
+			z.closeTag(&buf, tag.name)
+		case voidElements[tag.name] || tag.selfClosing:
+			z.writeTag(&buf, tag)
+		default:
+			z.writeTag(&buf, tag)
+			z.stack = append(z.stack, tag.name)
+		}
+	}
+	return buf.String()
+}
+
+// closeAll returns the closing tags of the elements left open.
+func (z *htmlSanitizer) closeAll() string {
+	if len(z.stack) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	z.closeTag(&buf, z.stack[0])
+	return buf.String()
+}
+
+func ruleSanitizeHTML(s *stateCore) {
+	policy := s.md.HTMLPolicy
+	if policy == nil {
+		return
+	}
+
+	block := htmlSanitizer{policy: policy, validate: s.md.allowsURL}
+	for _, tok := range s.tokens {
+		switch tok := tok.(type) {
+		case *HTMLBlock:
+			tok.Content = block.sanitize(tok.Content)
+		case *Inline:
+			inline := htmlSanitizer{policy: policy, validate: s.md.allowsURL}
+			children := tok.Children[:0]
+			lvl := 0
+			for _, itok := range tok.Children {
+				if h, ok := itok.(*HTMLInline); ok {
+					h.Content = inline.sanitize(h.Content)
+					lvl = h.Lvl
+					if h.Content == "" {
+						continue
+					}
+				} else if inline.skip != "" {
+					continue
+				}
+				children = append(children, itok)
+			}
+			if closing := inline.closeAll(); closing != "" {
+				s.env.countToken()
+				children = append(children, &HTMLInline{
+					Content: closing,
+					Lvl:     lvl,
+				})
+			}
+			tok.Children = children
+		}
+	}
+
+	if closing := block.closeAll(); closing != "" {
+		s.env.countToken()
+		s.tokens = append(s.tokens, &HTMLBlock{
+			Content: closing + "\n",
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package markdown
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"<div class=\"x\">\n*a*\n</div>", "<div>\n*a*\n</div>"},
+		{"Press <kbd>Ctrl</kbd>+<kbd>C</kbd>", "<p>Press <kbd>Ctrl</kbd>+<kbd>C</kbd></p>\n"},
+		{"a <span style=\"color:red\" onclick=\"x()\" title='t \"q\"'>b</span>", "<p>a <span title=\"t &quot;q&quot;\">b</span></p>\n"},
+		{"<a href=\"javascript:alert(1)\">x</a> <a href=\"/ok\">y</a>", "<p><a>x</a> <a href=\"/ok\">y</a></p>\n"},
+		{"a <script>alert(1)</script> b", "<p>a  b</p>\n"},
+		{"<script>\nalert(1)\n</script>\n\ntext", "\n<p>text</p>\n"},
+		{"a <em>b", "<p>a <em>b</em></p>\n"},
+		{"a </em> b", "<p>a  b</p>\n"},
+		{"<div>\n\n*a*", "<div>\n<p><em>a</em></p>\n</div>\n"},
+		{"a <!-- comment --> <form action=\"/x\">b</form>", "<p>a  b</p>\n"},
+		{"<img src=\"a.png\" onerror=\"x()\"> <br/>", "<p><img src=\"a.png\"> <br /></p>\n"},
+
+		// Entity-obfuscated schemes are decoded before the check.
+		{"<a href=\"&#106avascript:alert(1)\">x</a>", "<p><a>x</a></p>\n"},
+		{"<a href=\"&#x6A;avascript:alert(1)\">x</a>", "<p><a>x</a></p>\n"},
+		{"<a href=\"&#0000106&#0000097vascript:alert(1)\">x</a>", "<p><a>x</a></p>\n"},
+		{"<a href=\"javascript&colon;alert(1)\">x</a>", "<p><a>x</a></p>\n"},
+		{"<a href=\"java&#9;script:alert(1)\">x</a>", "<p><a>x</a></p>\n"},
+		{"<a href=' javascript:alert(1)'>x</a>", "<p><a>x</a></p>\n"},
+
+		// Values are written back escaped.
+		{"<a href=\"/a?b=1&amp;c=2\" title='x > \"y\" &lt;z'>x</a>", "<p><a href=\"/a?b=1&amp;c=2\" title=\"x &gt; &quot;y&quot; &lt;z\">x</a></p>\n"},
+		{"<a href=\"/a?b&c\">x</a>", "<p><a href=\"/a?b&amp;c\">x</a></p>\n"},
+		{"<span title=\"&#0;&#x110000;\">x</span>", "<p><span title=\"\ufffd\ufffd\">x</span></p>\n"},
+	}, HTML(true), SanitizeHTML(DefaultHTMLPolicy()), Typographer(false))
+
+	runRenderTests(t, []renderTest{
+		{"<form action=\"javascript:x()\"><button formaction=\"&#106;avascript:x()\">b</button></form>", "<form><button>b</button></form>"},
+		{"<table background=\"javascript:x\"></table><video poster=\"vbscript:x\"></video>", "<table></table><video></video>"},
+		{"<svg><a xlink:href=\"javascript:x\">a</a></svg>", "<p><svg><a>a</a></svg></p>\n"},
+		{"<img srcset=\"a.png 1x, javascript:x 2x\"><img srcset=\"a.png 1x, b.png 2x\">", "<p><img><img srcset=\"a.png 1x, b.png 2x\"></p>\n"},
+		{"<form action=\"/post\"></form>", "<form action=\"/post\"></form>"},
+	}, HTML(true), SanitizeHTML(&HTMLPolicy{Tags: map[string][]string{
+		"a":      {"xlink:href"},
+		"button": {"formaction"},
+		"form":   {"action"},
+		"img":    {"srcset"},
+		"svg":    nil,
+		"table":  {"background"},
+		"video":  {"poster"},
+	}}), Typographer(false))
+}
@@ -85,8 +85,8 @@ func replaceQuotes(tokens []Token, s *stateCore) {
 
 				isLastSpaceOrStart := index == 0 || unicode.IsSpace(lastChar)
 				isNextSpaceOrEnd := pos == max || unicode.IsSpace(nextChar)
-				isLastPunct := !isLastSpaceOrStart && (isMarkdownPunct(lastChar) || unicode.IsPunct(lastChar))
-				isNextPunct := !isNextSpaceOrEnd && (isMarkdownPunct(nextChar) || unicode.IsPunct(nextChar))
+				isLastPunct := !isLastSpaceOrStart && isPunct(lastChar)
+				isNextPunct := !isNextSpaceOrEnd && isPunct(nextChar)
 
 				if isNextSpaceOrEnd {
 					canOpen = false
@@ -16,7 +16,9 @@ package markdown
 const (
 	ptRoot = iota
 	ptList
+	ptParagraph
 	ptBlockQuote
+	ptContainer
 )
 
 type stateBlock struct {
@@ -24,12 +26,15 @@ type stateBlock struct {
 
 	bMarks     []int // offsets of the line beginnings
 	eMarks     []int // offsets of the line endings
-	tShift     []int // indents for each line
+	tShift     []int // offsets of the first non-space characters
+	sCount     []int // indents in columns, or -1 for the lazy lines
+	bsCount    []int // columns of the block markers cut off the lines
 	blkIndent  int   // required block content indent (in a list etc.)
 	line       int   // line index in the source string
 	lineMax    int   // number of lines
 	tight      bool  // loose or tight mode for lists
 	parentType byte  // parent block type
+	listIndent int   // indent of the innermost list, or -1
 	level      int
 }
 
@@ -46,12 +51,22 @@ func (s *stateBlock) skipEmptyLines(from int) int {
 
 func (s *stateBlock) skipSpaces(pos int) int {
 	src := s.src
-	for pos < len(src) && src[pos] == ' ' {
+	for pos < len(src) && (src[pos] == ' ' || src[pos] == '\t') {
 		pos++
 	}
 	return pos
 }
 
+func (s *stateBlock) skipSpacesBack(pos, min int) int {
+	for pos > min {
+		pos--
+		if s.src[pos] != ' ' && s.src[pos] != '\t' {
+			return pos + 1
+		}
+	}
+	return pos
+}
+
 func (s *stateBlock) skipBytes(pos int, b byte) int {
 	src := s.src
 	for pos < len(src) && src[pos] == b {
@@ -70,27 +85,50 @@ func (s *stateBlock) skipBytesBack(pos int, b byte, min int) int {
 	return pos
 }
 
+// lineStart returns the offset of the line after at most indent columns
+// of its indentation, and the number of columns left over from a tab that
+// is only partly cut off, to be made up with spaces.
+func (s *stateBlock) lineStart(line, indent int) (first, pad int) {
+	src := s.src
+	start := s.bMarks[line]
+	max := s.eMarks[line]
+	first = start
+	lineIndent := 0
+	for first < max && lineIndent < indent {
+		switch b := src[first]; {
+		case b == '\t':
+			lineIndent += 4 - (lineIndent+s.bsCount[line])%4
+		case b == ' ' || first-start < s.tShift[line]:
+			// The list markers before the tShift count as spaces.
+			lineIndent++
+		default:
+			return first, 0
+		}
+		first++
+	}
+	if lineIndent > indent {
+		pad = lineIndent - indent
+	}
+	return first, pad
+}
+
 func (s *stateBlock) lines(begin, end, indent int, keepLastLf bool) string {
-	if begin == end {
+	if begin >= end {
 		return ""
 	}
 
 	src := s.src
+	const spaces = "    "
 
 	if begin+1 == end {
-		shift := s.tShift[begin]
-		if shift < 0 {
-			shift = 0
-		} else if shift > indent {
-			shift = indent
-		}
-		first := s.bMarks[begin] + shift
-
+		first, pad := s.lineStart(begin, indent)
 		last := s.eMarks[begin]
 		if keepLastLf && last < len(src) {
 			last++
 		}
-
+		if pad > 0 {
+			return spaces[:pad] + src[first:last]
+		}
 		return src[first:last]
 	}
 
@@ -99,23 +137,20 @@ func (s *stateBlock) lines(begin, end, indent int, keepLastLf bool) string {
 	var previousLast int
 	adjoin := true
 	for line := begin; line < end; line++ {
-		shift := s.tShift[line]
-		if shift < 0 {
-			shift = 0
-		} else if shift > indent {
-			shift = indent
-		}
-		first := s.bMarks[line] + shift
+		first, pad := s.lineStart(line, indent)
 		last := s.eMarks[line]
 		if line+1 < end || (keepLastLf && last < len(src)) {
 			last++
 		}
-		size += last - first
+		size += pad + last - first
 		if line == begin {
 			firstFirst = first
 		} else if previousLast != first {
 			adjoin = false
 		}
+		if pad > 0 {
+			adjoin = false
+		}
 		previousLast = last
 	}
 
@@ -126,18 +161,13 @@ func (s *stateBlock) lines(begin, end, indent int, keepLastLf bool) string {
 	buf := make([]byte, size)
 	i := 0
 	for line := begin; line < end; line++ {
-		shift := s.tShift[line]
-		if shift < 0 {
-			shift = 0
-		} else if shift > indent {
-			shift = indent
-		}
-		first := s.bMarks[line] + shift
+		first, pad := s.lineStart(line, indent)
 		last := s.eMarks[line]
 		if line+1 < end || (keepLastLf && last < len(src)) {
 			last++
 		}
 
+		i += copy(buf[i:], spaces[:pad])
 		i += copy(buf[i:], src[first:last])
 	}
 
@@ -147,16 +177,19 @@ func (s *stateBlock) lines(begin, end, indent int, keepLastLf bool) string {
 func (s *stateBlock) pushToken(tok Token) {
 	tok.SetLevel(s.level)
 	s.tokens = append(s.tokens, tok)
+	s.env.countToken()
 }
 
 func (s *stateBlock) pushOpeningToken(tok Token) {
 	tok.SetLevel(s.level)
 	s.level++
 	s.tokens = append(s.tokens, tok)
+	s.env.countToken()
 }
 
 func (s *stateBlock) pushClosingToken(tok Token) {
 	s.level--
 	tok.SetLevel(s.level)
 	s.tokens = append(s.tokens, tok)
+	s.env.countToken()
 }
@@ -24,7 +24,10 @@ type stateInline struct {
 	pending      bytes.Buffer
 	pendingLevel int
 
+	delimiters []delimiter // emphasis runs of the scopes being parsed
+
 	cache map[int]int
+	scan  *scanCache // nil unless in the hardened mode
 }
 
 func (s *stateInline) pushToken(tok Token) {
@@ -34,6 +37,7 @@ func (s *stateInline) pushToken(tok Token) {
 	tok.SetLevel(s.level)
 	s.pendingLevel = s.level
 	s.tokens = append(s.tokens, tok)
+	s.env.countToken()
 }
 
 func (s *stateInline) pushOpeningToken(tok Token) {
@@ -44,6 +48,7 @@ func (s *stateInline) pushOpeningToken(tok Token) {
 	s.level++
 	s.pendingLevel = s.level
 	s.tokens = append(s.tokens, tok)
+	s.env.countToken()
 }
 
 func (s *stateInline) pushClosingToken(tok Token) {
@@ -54,12 +59,14 @@ func (s *stateInline) pushClosingToken(tok Token) {
 	tok.SetLevel(s.level)
 	s.pendingLevel = s.level
 	s.tokens = append(s.tokens, tok)
+	s.env.countToken()
 }
 
 func (s *stateInline) pushPending() {
-	s.tokens = append(s.tokens, &Text{
+	s.tokens = append(s.tokens, s.env.arena.text(Text{
 		Content: s.pending.String(),
 		Lvl:     s.pendingLevel,
-	})
+	}))
 	s.pending.Reset()
+	s.env.countToken()
 }
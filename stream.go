@@ -0,0 +1,303 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// RefPolicy tells ParseStream what to do about the reference definitions
+// that come after the links using them.
+type RefPolicy byte
+
+const (
+	// RefsForward applies a definition to the blocks after it only, so
+	// that a link to a reference defined later stays text.
+	RefsForward RefPolicy = iota
+
+	// RefsTwoPass reads the input twice, collecting the definitions in
+	// the first pass. The reader must be an io.Seeker.
+	RefsTwoPass
+)
+
+var errNotSeekable = errors.New("markdown: RefsTwoPass needs an io.Seeker")
+
+// ParseStream reads markdown from r and calls fn with the tokens of each
+// group of top-level blocks as soon as the input shows that they are
+// closed: after a blank line, at a line that cannot continue them. Only
+// the current group is kept in memory, so a single huge block, such as
+// an unclosed fence, still has to be read in full. The Map line numbers
+// of the tokens are relative to their group.
+func (m *Markdown) ParseStream(r io.Reader, fn func([]Token) error) error {
+	env := &environment{}
+
+	if m.StreamRefs == RefsTwoPass {
+		seeker, ok := r.(io.Seeker)
+		if !ok {
+			return errNotSeekable
+		}
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		err = m.streamBlocks(r, env, func(*stateCore) error { return nil })
+		if err != nil {
+			return err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		env.figures = 0
+	}
+
+	return m.streamBlocks(r, env, func(s *stateCore) error {
+		m.runCoreRules(s)
+		if len(s.tokens) == 0 {
+			return nil
+		}
+		return fn(s.tokens)
+	})
+}
+
+// RenderStream renders the markdown read from r to w group by group, see
+// ParseStream.
+func (m *Markdown) RenderStream(w io.Writer, r io.Reader) error {
+	renderer := NewRenderer(w)
+	return m.ParseStream(r, func(tokens []Token) error {
+		return renderer.Render(tokens, m.renderOptions)
+	})
+}
+
+func isBlankLine(line []byte) bool {
+	for _, b := range line {
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// listMarker is the kind of the marker of a list item: its bullet, or the
+// delimiter after the number of an ordered list item. Items with other
+// kinds of markers do not continue a list.
+type listMarker struct {
+	ordered bool
+	char    byte
+}
+
+// listMarkerOf returns the marker of the list item starting the line, if
+// any.
+func listMarkerOf(line []byte) (m listMarker, ok bool) {
+	line = bytes.TrimLeft(line, " ")
+	pos := 0
+	switch {
+	case len(line) == 0:
+		return m, false
+	case line[0] == '-' || line[0] == '+' || line[0] == '*':
+		m.char = line[0]
+		pos = 1
+	default:
+		for pos < len(line) && pos < 10 && line[pos] >= '0' && line[pos] <= '9' {
+			pos++
+		}
+		if pos == 0 || pos == 10 || pos == len(line) || line[pos] != '.' && line[pos] != ')' {
+			return m, false
+		}
+		m.ordered = true
+		m.char = line[pos]
+		pos++
+	}
+	if pos < len(line) && !isBlankLine(line[pos:pos+1]) {
+		return m, false
+	}
+	return m, true
+}
+
+// lineAt returns the nth line of src, counting lines the way countLines
+// does.
+func lineAt(src []byte, n int) []byte {
+	start := 0
+	for i := 0; i < len(src); i++ {
+		if src[i] != '\n' && src[i] != '\r' {
+			continue
+		}
+		if src[i] == '\r' && i+1 < len(src) && src[i+1] == '\n' {
+			i++
+		}
+		if n == 0 {
+			return src[start : i+1]
+		}
+		n--
+		start = i + 1
+	}
+	return src[start:]
+}
+
+// fenceCloser returns a function reporting whether a line may close the
+// fence opened by the line open.
+func fenceCloser(open []byte) func([]byte) bool {
+	open = bytes.TrimLeft(open, " ")
+	c := open[0]
+	n := len(open) - len(bytes.TrimLeft(open, string(c)))
+	return func(line []byte) bool {
+		line = bytes.TrimLeft(line, " ")
+		rest := bytes.TrimLeft(line, string(c))
+		return len(line)-len(rest) >= n && isBlankLine(rest)
+	}
+}
+
+func containerCloser(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, " "), []byte(":::"))
+}
+
+// htmlBlockCloser returns a function reporting whether a line ends the
+// HTML block started by the line open, or nil if the block ends at a
+// blank line.
+func htmlBlockCloser(open []byte) func([]byte) bool {
+	kind := htmlBlockKind(string(bytes.TrimLeft(open, " \t")))
+	if kind == 0 || kind > 5 {
+		return nil
+	}
+	return func(line []byte) bool {
+		return htmlBlockEnds(kind, string(line))
+	}
+}
+
+// grouper decides where the groups of top-level blocks of ParseStream
+// end. A group may only end before an unindented line that follows a
+// blank line; whether it does is up to the block parser, and the parse of
+// the group tells how its last block goes on: a list with the markers of
+// its items, a fence, a container or an HTML block until a line that
+// closes it. This is kept so that the group is parsed again only at the
+// lines that may end its last block.
+type grouper struct {
+	md        *Markdown
+	lastBlank bool
+	closer    func([]byte) bool // set while the last block is open
+	list      listMarker        // the marker of the last block, if a list
+}
+
+// mayEnd is called with each line before it is added to the group and
+// reports whether the group may end before it.
+func (g *grouper) mayEnd(line []byte) bool {
+	lastBlank := g.lastBlank
+	g.lastBlank = isBlankLine(line)
+
+	if g.closer != nil {
+		if g.closer(line) {
+			g.closer = nil
+		}
+		return false
+	}
+	if !lastBlank || g.lastBlank || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+	if m, ok := listMarkerOf(line); ok && g.list.char != 0 && m == g.list {
+		return false
+	}
+	// A Table: line may be the caption of the table before it.
+	if g.md.ExtendedTables && bytes.HasPrefix(line, []byte("Table:")) {
+		return false
+	}
+	return true
+}
+
+// ends reports whether the group with the source src and the block
+// tokens ends before the line. If it does not, it remembers how its last
+// block goes on.
+func (g *grouper) ends(src []byte, tokens []Token, line []byte) bool {
+	g.list = listMarker{}
+
+	var last Token
+	lastItem := -1
+	for i, tok := range tokens {
+		if tok.Level() == 0 && !tok.Closing() {
+			last = tok
+		} else if _, ok := tok.(*ListItemOpen); ok && tok.Level() == 1 {
+			lastItem = i
+		}
+	}
+
+	lines := countLines(src)
+	switch tok := last.(type) {
+	case *Fence:
+		if tok.Map[1] >= lines {
+			g.closer = fenceCloser(lineAt(src, tok.Map[0]))
+			return false
+		}
+	case *ContainerOpen:
+		if tok.Map[1] >= lines {
+			g.closer = containerCloser
+			return false
+		}
+	case *HTMLBlock:
+		if tok.Map[1] >= lines {
+			if g.closer = htmlBlockCloser(lineAt(src, tok.Map[0])); g.closer != nil {
+				return false
+			}
+		}
+	case *BulletListOpen, *OrderedListOpen:
+		if lastItem < 0 {
+			break
+		}
+		g.list, _ = listMarkerOf(lineAt(src, tokens[lastItem].(*ListItemOpen).Map[0]))
+		if m, ok := listMarkerOf(line); ok && m == g.list {
+			return false
+		}
+		g.list = listMarker{}
+	}
+	return true
+}
+
+// streamBlocks splits the input into groups of closed top-level blocks
+// and calls fn with the core state of each after the block parsing.
+func (m *Markdown) streamBlocks(r io.Reader, env *environment, fn func(*stateCore) error) error {
+	br := bufio.NewReader(r)
+	var buf []byte
+	g := grouper{md: m}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if g.mayEnd(line) && len(buf) > 0 {
+				s := &stateCore{md: m, env: env}
+				s.tokens = m.block.parse(buf, m, env)
+				if g.ends(buf, s.tokens, line) {
+					if err := fn(s); err != nil {
+						return err
+					}
+					buf = buf[:0]
+				}
+			}
+			buf = append(buf, line...)
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(buf) == 0 {
+		return nil
+	}
+	s := &stateCore{md: m, env: env}
+	s.tokens = m.block.parse(buf, m, env)
+	return fn(s)
+}
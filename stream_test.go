@@ -0,0 +1,126 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderStream(t *testing.T) {
+	docs := []string{
+		"# Title\n\nSome *text*.\n\n## Section\n\nMore text\nover two lines.\n",
+		"- a\n- b\n\n- c\n\n  continued\n\nAfter the list.\n",
+		"1. one\n\n2. two\n\ntext",
+		"```\ncode\n\nwith blank lines\n\n```\n\ntext\n",
+		"```\nunclosed\n\nfence\n",
+		"    indented\n\n    code\n\ntext\n",
+		"> quote\n\n> another\n\ntext\n",
+		"<div>\n\n*a*\n\n</div>\n\ntext\n",
+		"[a]: /a\n\n[link][a]\n\n[b]: /b\n\n[b]\n",
+		"a | b\n--|--\n1 | 2\n\nTable: caption\n\ntext\n",
+		"![one](/1.png \"One\")\n\n![two](/2.png \"Two\")\n",
+		"text\r\n\r\nmore\r\n",
+		"- a\n\n- b\n\n* c\n\n1. d\n\n2) e\n\n3) f\n\n---\n\n- g\n\ntext\n",
+		"- a\n\n  b\n\n      code\n\n-\n\nc\n",
+		"text\n\n- a\n```\nfence\n\n- b\n```\n\nafter\n",
+		"````\na\n\n```\n\nb\n````\n\n~~~\n\n```\n\n~~~\n",
+		"<!--\n\ncomment\n\n-->\n\n<script>\n\nx\n\n</script>\n\n<div>\n\ntext\n",
+		"::: note\n\n::: tip\n\na\n\n:::\n\nb\n\n:::\n\nc\n",
+	}
+	md := New(HTML(true), ExtendedTables(true), Figures(true), NumberFigures("Figure"), Containers(true))
+	for _, doc := range docs {
+		var buf bytes.Buffer
+		if err := md.RenderStream(&buf, strings.NewReader(doc)); err != nil {
+			t.Errorf("RenderStream(%q): %v", doc, err)
+			continue
+		}
+		if want := md.RenderToString([]byte(doc)); buf.String() != want {
+			t.Errorf("RenderStream(%q) = %q, want %q", doc, buf.String(), want)
+		}
+	}
+}
+
+func TestParseStreamGroups(t *testing.T) {
+	doc := "# A\n\ntext\n\n- a\n\n- b\n\n```\nx\n\ny\n```\n\nend\n"
+	var groups []int
+	err := New().ParseStream(strings.NewReader(doc), func(tokens []Token) error {
+		groups = append(groups, len(tokens))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The items of the loose list make one group.
+	if len(groups) != 5 {
+		t.Errorf("got %d groups of tokens, want 5", len(groups))
+	}
+}
+
+// readCounter counts the bytes read from a reader.
+type readCounter struct {
+	r io.Reader
+	n int
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestParseStreamLists(t *testing.T) {
+	var doc strings.Builder
+	for i := 0; doc.Len() < 1<<20; i++ {
+		fmt.Fprintf(&doc, "- item %d\n- item\n\n+ item\n\n  more\n\n1. one\n2. two\n\n", i)
+	}
+
+	md := New()
+	r := &readCounter{r: strings.NewReader(doc.String())}
+	var buf bytes.Buffer
+	read := -1
+	err := md.ParseStream(r, func(tokens []Token) error {
+		if read < 0 {
+			read = r.n
+		}
+		return md.RenderTokens(&buf, tokens)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read < 0 || read >= doc.Len() {
+		t.Errorf("the first tokens came after reading %d of %d bytes", read, doc.Len())
+	}
+	if want := md.RenderToString([]byte(doc.String())); buf.String() != want {
+		t.Error("ParseStream renders the lists differently from Parse")
+	}
+}
+
+func TestStreamReferences(t *testing.T) {
+	const doc = "[later] link\n\n[later]: /later\n"
+
+	var buf bytes.Buffer
+	if err := New().RenderStream(&buf, strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<p>[later] link</p>\n"; buf.String() != want {
+		t.Errorf("RefsForward: got %q, want %q", buf.String(), want)
+	}
+
+	md := New(StreamReferences(RefsTwoPass))
+	buf.Reset()
+	if err := md.RenderStream(&buf, bytes.NewReader([]byte(doc))); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<p><a href=\"/later\">later</a> link</p>\n"; buf.String() != want {
+		t.Errorf("RefsTwoPass: got %q, want %q", buf.String(), want)
+	}
+
+	if err := md.RenderStream(&buf, strings.NewReader(doc)); err != nil {
+		t.Errorf("RefsTwoPass with a strings.Reader: %v", err)
+	}
+	if err := md.RenderStream(&buf, bytes.NewBufferString(doc)); err == nil {
+		t.Error("RefsTwoPass with a bytes.Buffer: got no error")
+	}
+}
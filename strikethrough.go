@@ -26,6 +26,10 @@ func ruleStrikeThrough(s *stateInline, silent bool) (_ bool) {
 		return
 	}
 
+	if s.md.GFM {
+		return gfmStrikethrough(s)
+	}
+
 	canOpen, canClose, delims := scanDelims(s, start)
 	startCount := delims
 	if !canOpen {
@@ -38,6 +42,9 @@ func ruleStrikeThrough(s *stateInline, silent bool) (_ bool) {
 	if stack <= 0 {
 		return
 	}
+	if c := s.hardened(); c != nil && c.noCloserAfter('~', start+startCount) {
+		return
+	}
 	s.pos = start + startCount
 
 	var found bool
@@ -59,6 +66,9 @@ func ruleStrikeThrough(s *stateInline, silent bool) (_ bool) {
 
 			if canOpen {
 				stack += tagCount
+				if s.tooDeep(stack) {
+					break
+				}
 			}
 			s.pos += count
 			continue
@@ -86,3 +96,57 @@ func ruleStrikeThrough(s *stateInline, silent bool) (_ bool) {
 
 	return true
 }
+
+// gfmStrikethrough handles the GFM strikethrough, which is delimited by
+// runs of one or two tildes of the same length.
+func gfmStrikethrough(s *stateInline) bool {
+	start := s.pos
+	max := s.posMax
+	src := s.src
+
+	canOpen, _, startCount := scanDelims(s, start)
+	s.pos = start + startCount
+	if !canOpen || startCount > 2 {
+		s.pending.WriteString(src[start:s.pos])
+		return true
+	}
+	if c := s.hardened(); c != nil && c.noCloserAfter('~', s.pos) {
+		s.pending.WriteString(src[start:s.pos])
+		return true
+	}
+
+	found := false
+	for s.pos < max {
+		if src[s.pos] == '~' {
+			_, canClose, count := scanDelims(s, s.pos)
+			if canClose && count == startCount {
+				found = true
+				break
+			}
+			s.pos += count
+			continue
+		}
+
+		s.md.inline.skipToken(s)
+	}
+
+	if !found {
+		s.pos = start + startCount
+		s.pending.WriteString(src[start:s.pos])
+		return true
+	}
+
+	s.posMax = s.pos
+	s.pos = start + startCount
+
+	s.pushOpeningToken(&StrikethroughOpen{})
+
+	s.md.inline.tokenize(s)
+
+	s.pushClosingToken(&StrikethroughClose{})
+
+	s.pos = s.posMax + startCount
+	s.posMax = max
+
+	return true
+}
@@ -24,7 +24,7 @@ func init() {
 }
 
 func getLine(s *stateBlock, line int) string {
-	pos := s.bMarks[line] + s.blkIndent
+	pos := s.bMarks[line] + s.tShift[line]
 	max := s.eMarks[line]
 	if pos >= max {
 		return ""
@@ -75,6 +75,136 @@ func escapedSplit(s string) (result []string) {
 	return
 }
 
+type tableCell struct {
+	content string
+	colspan int
+}
+
+func (c tableCell) span() int {
+	if c.colspan > 1 {
+		return c.colspan
+	}
+	return 1
+}
+
+// gfmSplit splits a table row at the pipes that are not escaped with a
+// backslash, even inside code spans, and unescapes the escaped ones.
+func gfmSplit(s string) (result []string) {
+	if len(s) > 0 && s[len(s)-1] == '|' && (len(s) < 2 || s[len(s)-2] != '\\') {
+		s = s[:len(s)-1]
+	}
+	if len(s) > 0 && s[0] == '|' {
+		s = s[1:]
+	}
+
+	var buf strings.Builder
+	for pos := 0; pos < len(s); pos++ {
+		b := s[pos]
+		switch {
+		case b == '\\' && pos+1 < len(s) && s[pos+1] == '|':
+			buf.WriteByte('|')
+			pos++
+		case b == '|':
+			result = append(result, buf.String())
+			buf.Reset()
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	return append(result, buf.String())
+}
+
+// splitTableRow splits a table row into trimmed cells. In the extended
+// mode an empty cell (as in "a ||") widens the cell before it.
+func splitTableRow(lineText string, extended, gfm bool) (cells []tableCell) {
+	split := escapedSplit
+	if gfm {
+		split = gfmSplit
+	}
+	for _, c := range split(lineText) {
+		if extended && c == "" && len(cells) > 0 {
+			last := &cells[len(cells)-1]
+			last.colspan = last.span() + 1
+			continue
+		}
+		cells = append(cells, tableCell{content: strings.TrimSpace(c)})
+	}
+	return
+}
+
+func columnCount(cells []tableCell) (n int) {
+	for _, c := range cells {
+		n += c.span()
+	}
+	return
+}
+
+// joinTableRows appends the cells of a continuation line to the cells of
+// a multi-line row.
+func joinTableRows(cells, more []tableCell) []tableCell {
+	if cells == nil {
+		return more
+	}
+	for i, c := range more {
+		if i >= len(cells) {
+			cells = append(cells, c)
+			continue
+		}
+		if c.content == "" {
+			continue
+		}
+		if cells[i].content != "" {
+			cells[i].content += "\n"
+		}
+		cells[i].content += c.content
+	}
+	return cells
+}
+
+// fitTableRow pads or truncates the row to the given number of columns.
+// Spans that overflow shrink first, from the last one, so that the cells
+// after them keep their content; the cells still left over are dropped.
+func fitTableRow(cells []tableCell, columns int) []tableCell {
+	n := 0
+	for _, c := range cells {
+		n += c.span()
+	}
+	for i := len(cells) - 1; i >= 0 && n > columns; i-- {
+		if span := cells[i].span(); span > 1 {
+			shrink := span - 1
+			if shrink > n-columns {
+				shrink = n - columns
+			}
+			cells[i].colspan = span - shrink
+			n -= shrink
+		}
+	}
+	if len(cells) > columns {
+		cells = cells[:columns]
+		n = columns
+	}
+	for ; n < columns; n++ {
+		cells = append(cells, tableCell{})
+	}
+	return cells
+}
+
+func interruptsTable(s *stateBlock, line, endLine int) bool {
+	for _, r := range []blockRule{
+		ruleFence,
+		ruleBlockQuote,
+		ruleHR,
+		ruleList,
+		ruleHeading,
+		ruleHTMLBlock,
+	} {
+		if r(s, line, endLine, true) {
+			return true
+		}
+	}
+	return false
+}
+
 func ruleTable(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 	if !s.md.Tables {
 		return
@@ -86,7 +216,7 @@ func ruleTable(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 
 	nextLine := startLine + 1
 
-	if s.tShift[nextLine] < s.blkIndent {
+	if s.sCount[nextLine] < s.blkIndent {
 		return
 	}
 
@@ -129,13 +259,16 @@ func ruleTable(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 		}
 	}
 
+	extended := s.md.ExtendedTables
+	gfm := s.md.GFM
+
 	lineText = strings.TrimSpace(getLine(s, startLine))
 	if strings.IndexByte(lineText, '|') == -1 {
 		return
 	}
 
-	rows = escapedSplit(lineText)
-	if len(aligns) != len(rows) {
+	cells := splitTableRow(lineText, extended, gfm)
+	if len(aligns) != columnCount(cells) {
 		return
 	}
 
@@ -146,6 +279,7 @@ func ruleTable(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 	tableTok := &TableOpen{
 		Map: [2]int{startLine, 0},
 	}
+	tableIdx := len(s.tokens)
 	s.pushOpeningToken(tableTok)
 	s.pushOpeningToken(&TheadOpen{
 		Map: [2]int{startLine, startLine + 1},
@@ -154,60 +288,143 @@ func ruleTable(s *stateBlock, startLine, endLine int, silent bool) (_ bool) {
 		Map: [2]int{startLine, startLine + 1},
 	})
 
-	for i := 0; i < len(rows); i++ {
+	col := 0
+	for _, cell := range cells {
 		s.pushOpeningToken(&ThOpen{
-			Align: aligns[i],
-			Map:   [2]int{startLine, startLine + 1},
+			Align:   aligns[col],
+			Colspan: cell.colspan,
+			Map:     [2]int{startLine, startLine + 1},
 		})
 		s.pushToken(&Inline{
-			Content: strings.TrimSpace(rows[i]),
+			Content: cell.content,
 			Map:     [2]int{startLine, startLine + 1},
 		})
 		s.pushClosingToken(&ThClose{})
+		col += cell.span()
 	}
 
 	s.pushClosingToken(&TrClose{})
 	s.pushClosingToken(&TheadClose{})
 
-	tbodyTok := &TbodyOpen{
-		Map: [2]int{startLine + 2, 0},
+	// GFM leaves out the body of a table with no rows.
+	var tbodyTok *TbodyOpen
+	if !gfm {
+		tbodyTok = &TbodyOpen{
+			Map: [2]int{startLine + 2, 0},
+		}
+		s.pushOpeningToken(tbodyTok)
 	}
-	s.pushOpeningToken(tbodyTok)
 
+	above := make([]*TdOpen, len(aligns))
 	for nextLine = startLine + 2; nextLine < endLine; nextLine++ {
-		shift := s.tShift[nextLine]
+		shift := s.sCount[nextLine]
 		if shift >= 0 && shift < s.blkIndent {
 			break
 		}
 
 		lineText = strings.TrimSpace(getLine(s, nextLine))
-		if strings.IndexByte(lineText, '|') == -1 {
+		if gfm {
+			// GFM tables end at a blank line or at the start of
+			// another block; the rows may lack pipes.
+			if lineText == "" || interruptsTable(s, nextLine, endLine) {
+				break
+			}
+		} else if strings.IndexByte(lineText, '|') == -1 {
 			break
 		}
-		rows = escapedSplit(lineText)
-		if len(rows) < len(aligns) {
-			rows = append(rows, make([]string, len(aligns)-len(rows))...)
-		} else if len(rows) > len(aligns) {
-			rows = rows[:len(aligns)]
+
+		if !extended {
+			cells = splitTableRow(lineText, false, gfm)
+		} else {
+			cells = nil
+			for {
+				continued := strings.HasSuffix(lineText, "\\") && !strings.HasSuffix(lineText, "\\\\")
+				if continued {
+					lineText = strings.TrimSpace(lineText[:len(lineText)-1])
+				}
+				cells = joinTableRows(cells, splitTableRow(lineText, true, gfm))
+				if !continued || nextLine+1 >= endLine || s.isLineEmpty(nextLine+1) {
+					break
+				}
+				nextLine++
+				lineText = strings.TrimSpace(getLine(s, nextLine))
+			}
+		}
+		cells = fitTableRow(cells, len(aligns))
+
+		if tbodyTok == nil {
+			tbodyTok = &TbodyOpen{
+				Map: [2]int{startLine + 2, 0},
+			}
+			s.pushOpeningToken(tbodyTok)
 		}
 
 		s.pushOpeningToken(&TrOpen{})
-		for i := 0; i < len(rows); i++ {
-			s.pushOpeningToken(&TdOpen{Align: aligns[i]})
+		col = 0
+		for _, cell := range cells {
+			span := cell.span()
+			if extended && cell.content == "^^" && above[col] != nil {
+				if above[col].Rowspan == 0 {
+					above[col].Rowspan = 1
+				}
+				above[col].Rowspan++
+				col += span
+				continue
+			}
+
+			tok := &TdOpen{
+				Align:   aligns[col],
+				Colspan: cell.colspan,
+			}
+			s.pushOpeningToken(tok)
 			s.pushToken(&Inline{
-				Content: strings.TrimSpace(rows[i]),
+				Content: cell.content,
 			})
 			s.pushClosingToken(&TdClose{})
+			for i := 0; i < span; i++ {
+				above[col+i] = tok
+			}
+			col += span
 		}
 		s.pushClosingToken(&TrClose{})
 	}
 
-	s.pushClosingToken(&TbodyClose{})
+	if tbodyTok != nil {
+		s.pushClosingToken(&TbodyClose{})
+		tbodyTok.Map[1] = nextLine
+	}
+
+	if extended {
+		captionLine := nextLine
+		if captionLine < endLine && s.isLineEmpty(captionLine) {
+			captionLine++
+		}
+		if captionLine < endLine && s.sCount[captionLine] >= s.blkIndent {
+			lineText = strings.TrimSpace(getLine(s, captionLine))
+			if strings.HasPrefix(lineText, "Table:") {
+				nextLine = captionLine + 1
+				insertCaption(s, tableIdx+1, strings.TrimSpace(lineText[len("Table:"):]), captionLine)
+			}
+		}
+	}
+
 	s.pushClosingToken(&TableClose{})
 
 	tableTok.Map[1] = nextLine
-	tbodyTok.Map[1] = nextLine
 	s.line = nextLine
 
 	return true
 }
+
+// insertCaption inserts the caption tokens at idx, right after the
+// TableOpen token, since the caption line follows the rows it belongs to.
+func insertCaption(s *stateBlock, idx int, caption string, line int) {
+	lvl := s.tokens[idx-1].Level() + 1
+	tokens := []Token{
+		&CaptionOpen{Map: [2]int{line, line + 1}, Lvl: lvl},
+		&Inline{Content: caption, Map: [2]int{line, line + 1}, Lvl: lvl + 1},
+		&CaptionClose{Lvl: lvl},
+	}
+	s.env.countTokens(len(tokens))
+	s.tokens = append(s.tokens[:idx], append(tokens, s.tokens[idx:]...)...)
+}
@@ -81,3 +81,63 @@ func TestIsHeaderLine(t *testing.T) {
 		}
 	}
 }
+
+func TestExtendedTables(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"a | b\n--|--\n1 | 2\n\nTable: *Totals*", "<table>\n<caption><em>Totals</em></caption>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"a | b | c\n--|--|--\n1 || 2", "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n<th>c</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td colspan=\"2\">1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"a | b\n--|--\n1 | 2\n^^ | 3", "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td rowspan=\"2\">1</td>\n<td>2</td>\n</tr>\n<tr>\n<td>3</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"| a | b |\n|--|--|\n| 1 | 2 \\\n|   | 3 |", "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2\n3</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"| a ||\n|--|--|\n| 1 | 2 |", "<table>\n<thead>\n<tr>\n<th colspan=\"2\">a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"a | b\n--|--\n1 ||||| 2", "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+	}, ExtendedTables(true))
+}
+
+func TestFitTableRow(t *testing.T) {
+	type testCase struct {
+		cells   []tableCell
+		columns int
+		want    []tableCell
+	}
+	testCases := []testCase{
+		{[]tableCell{{"1", 0}, {"2", 0}}, 3, []tableCell{{"1", 0}, {"2", 0}, {}}},
+		{[]tableCell{{"1", 0}, {"2", 0}, {"3", 0}}, 2, []tableCell{{"1", 0}, {"2", 0}}},
+		{[]tableCell{{"1", 5}, {"2", 0}}, 2, []tableCell{{"1", 1}, {"2", 0}}},
+		{[]tableCell{{"1", 4}, {"2", 0}}, 3, []tableCell{{"1", 2}, {"2", 0}}},
+		{[]tableCell{{"1", 2}, {"2", 2}}, 3, []tableCell{{"1", 2}, {"2", 1}}},
+		{[]tableCell{{"1", 2}, {"2", 0}, {"3", 0}}, 2, []tableCell{{"1", 1}, {"2", 0}}},
+	}
+	for _, tc := range testCases {
+		got := fitTableRow(append([]tableCell(nil), tc.cells...), tc.columns)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("fitTableRow(%v, %d) = %v, want %v", tc.cells, tc.columns, got, tc.want)
+		}
+	}
+}
+
+func TestTableAlignOutput(t *testing.T) {
+	const src = "a | b | c\n:-|:-:|--\n1 | 2 | 3"
+	table := func(left, center string) string {
+		return "<table>\n<thead>\n<tr>\n<th" + left + ">a</th>\n<th" + center + ">b</th>\n<th>c</th>\n</tr>\n</thead>\n" +
+			"<tbody>\n<tr>\n<td" + left + ">1</td>\n<td" + center + ">2</td>\n<td>3</td>\n</tr>\n</tbody>\n</table>\n"
+	}
+
+	type testCase struct {
+		opts []option
+		want string
+	}
+	testCases := []testCase{
+		{nil, table(` style="text-align:left"`, ` style="text-align:center"`)},
+		{[]option{AlignWith(AlignClass)}, table(` class="align-left"`, ` class="align-center"`)},
+		{[]option{AlignWith(AlignClass), AlignClassPrefix("md-")}, table(` class="md-left"`, ` class="md-center"`)},
+		{[]option{AlignWith(AlignAttr)}, table(` align="left"`, ` align="center"`)},
+	}
+	for _, tc := range testCases {
+		got, err := render(src, tc.opts...)
+		if err != nil {
+			t.Errorf("render(%q): %v", src, err)
+		} else if got != tc.want {
+			t.Errorf("render(%q) = %q, want %q", src, got, tc.want)
+		}
+	}
+}
@@ -23,12 +23,14 @@ type Token interface {
 }
 
 type BlockquoteOpen struct {
-	Map [2]int
-	Lvl int
+	Alert Alert
+	Map   [2]int
+	Lvl   int
 }
 
 type BlockquoteClose struct {
-	Lvl int
+	Alert Alert
+	Lvl   int
 }
 
 type BulletListOpen struct {
@@ -70,6 +72,18 @@ type CodeInline struct {
 	Lvl     int
 }
 
+type ContainerOpen struct {
+	Name  string
+	Title string
+	Map   [2]int
+	Lvl   int
+}
+
+type ContainerClose struct {
+	Name string
+	Lvl  int
+}
+
 type EmphasisOpen struct {
 	Lvl int
 }
@@ -96,11 +110,29 @@ type StrikethroughClose struct {
 
 type Fence struct {
 	Params  string
+	Lang    string
+	Attrs   FenceAttrs
 	Content string
 	Map     [2]int
 	Lvl     int
 }
 
+type FigureOpen struct {
+	ID      string // fig-N when figures are numbered
+	Number  int    // 0 when figures are not numbered
+	Label   string
+	Caption string
+	Map     [2]int
+	Lvl     int
+}
+
+type FigureClose struct {
+	Number  int
+	Label   string
+	Caption string
+	Lvl     int
+}
+
 type Softbreak struct {
 	Lvl int
 }
@@ -143,6 +175,33 @@ type Image struct {
 	Lvl    int
 }
 
+type TaskCheckbox struct {
+	Checked bool
+	Lvl     int
+}
+
+type IncludeOpen struct {
+	Path string
+	Map  [2]int
+	Lvl  int
+}
+
+type IncludeClose struct {
+	Path string
+	Lvl  int
+}
+
+// IncludeError takes the place of an include directive whose file could
+// not be included. Err is the error of the file system, ErrIncludeCycle or
+// ErrIncludeDepth.
+type IncludeError struct {
+	Path      string
+	Directive string
+	Err       error
+	Map       [2]int
+	Lvl       int
+}
+
 type Inline struct {
 	Content  string
 	Map      [2]int
@@ -154,6 +213,9 @@ type LinkOpen struct {
 	Href   string
 	Title  string
 	Target string
+	Rel    string
+	Class  string
+	Auto   bool // an autolink or a linkified URL
 	Lvl    int
 }
 
@@ -182,6 +244,15 @@ type TableClose struct {
 	Lvl int
 }
 
+type CaptionOpen struct {
+	Map [2]int
+	Lvl int
+}
+
+type CaptionClose struct {
+	Lvl int
+}
+
 type TheadOpen struct {
 	Map [2]int
 	Lvl int
@@ -201,9 +272,11 @@ type TrClose struct {
 }
 
 type ThOpen struct {
-	Align Align
-	Map   [2]int
-	Lvl   int
+	Align   Align
+	Colspan int // values below 2 mean no spanning
+	Rowspan int
+	Map     [2]int
+	Lvl     int
 }
 
 type ThClose struct {
@@ -220,9 +293,11 @@ type TbodyClose struct {
 }
 
 type TdOpen struct {
-	Align Align
-	Map   [2]int
-	Lvl   int
+	Align   Align
+	Colspan int // values below 2 mean no spanning
+	Rowspan int
+	Map     [2]int
+	Lvl     int
 }
 
 type TdClose struct {
@@ -244,6 +319,13 @@ var htags = []string{
 	"h6",
 }
 
+func blockquoteTag(a Alert) string {
+	if a != AlertNone {
+		return "div"
+	}
+	return "blockquote"
+}
+
 func (t *BlockquoteOpen) Level() int { return t.Lvl }
 
 func (t *BlockquoteClose) Level() int { return t.Lvl }
@@ -264,6 +346,10 @@ func (t *CodeBlock) Level() int { return t.Lvl }
 
 func (t *CodeInline) Level() int { return t.Lvl }
 
+func (t *ContainerOpen) Level() int { return t.Lvl }
+
+func (t *ContainerClose) Level() int { return t.Lvl }
+
 func (t *EmphasisOpen) Level() int { return t.Lvl }
 
 func (t *EmphasisClose) Level() int { return t.Lvl }
@@ -278,6 +364,10 @@ func (t *StrikethroughClose) Level() int { return t.Lvl }
 
 func (t *Fence) Level() int { return t.Lvl }
 
+func (t *FigureOpen) Level() int { return t.Lvl }
+
+func (t *FigureClose) Level() int { return t.Lvl }
+
 func (t *Softbreak) Level() int { return t.Lvl }
 
 func (t *Hardbreak) Level() int { return t.Lvl }
@@ -294,6 +384,14 @@ func (t *Hr) Level() int { return t.Lvl }
 
 func (t *Image) Level() int { return t.Lvl }
 
+func (t *TaskCheckbox) Level() int { return t.Lvl }
+
+func (t *IncludeOpen) Level() int { return t.Lvl }
+
+func (t *IncludeClose) Level() int { return t.Lvl }
+
+func (t *IncludeError) Level() int { return t.Lvl }
+
 func (t *Inline) Level() int { return t.Lvl }
 
 func (t *LinkOpen) Level() int { return t.Lvl }
@@ -308,6 +406,10 @@ func (t *TableOpen) Level() int { return t.Lvl }
 
 func (t *TableClose) Level() int { return t.Lvl }
 
+func (t *CaptionOpen) Level() int { return t.Lvl }
+
+func (t *CaptionClose) Level() int { return t.Lvl }
+
 func (t *TheadOpen) Level() int { return t.Lvl }
 
 func (t *TheadClose) Level() int { return t.Lvl }
@@ -350,6 +452,10 @@ func (t *CodeBlock) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *CodeInline) SetLevel(lvl int) { t.Lvl = lvl }
 
+func (t *ContainerOpen) SetLevel(lvl int) { t.Lvl = lvl }
+
+func (t *ContainerClose) SetLevel(lvl int) { t.Lvl = lvl }
+
 func (t *EmphasisOpen) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *EmphasisClose) SetLevel(lvl int) { t.Lvl = lvl }
@@ -364,6 +470,10 @@ func (t *StrikethroughClose) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *Fence) SetLevel(lvl int) { t.Lvl = lvl }
 
+func (t *FigureOpen) SetLevel(lvl int) { t.Lvl = lvl }
+
+func (t *FigureClose) SetLevel(lvl int) { t.Lvl = lvl }
+
 func (t *Softbreak) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *Hardbreak) SetLevel(lvl int) { t.Lvl = lvl }
@@ -380,6 +490,14 @@ func (t *Hr) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *Image) SetLevel(lvl int) { t.Lvl = lvl }
 
+func (t *TaskCheckbox) SetLevel(lvl int) { t.Lvl = lvl }
+
+func (t *IncludeOpen) SetLevel(lvl int) { t.Lvl = lvl }
+
+func (t *IncludeClose) SetLevel(lvl int) { t.Lvl = lvl }
+
+func (t *IncludeError) SetLevel(lvl int) { t.Lvl = lvl }
+
 func (t *Inline) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *LinkOpen) SetLevel(lvl int) { t.Lvl = lvl }
@@ -394,6 +512,10 @@ func (t *TableOpen) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *TableClose) SetLevel(lvl int) { t.Lvl = lvl }
 
+func (t *CaptionOpen) SetLevel(lvl int) { t.Lvl = lvl }
+
+func (t *CaptionClose) SetLevel(lvl int) { t.Lvl = lvl }
+
 func (t *TheadOpen) SetLevel(lvl int) { t.Lvl = lvl }
 
 func (t *TheadClose) SetLevel(lvl int) { t.Lvl = lvl }
@@ -436,6 +558,10 @@ func (t *CodeBlock) Opening() bool { return false }
 
 func (t *CodeInline) Opening() bool { return false }
 
+func (t *ContainerOpen) Opening() bool { return true }
+
+func (t *ContainerClose) Opening() bool { return false }
+
 func (t *EmphasisOpen) Opening() bool { return true }
 
 func (t *EmphasisClose) Opening() bool { return false }
@@ -450,6 +576,10 @@ func (t *StrikethroughClose) Opening() bool { return false }
 
 func (t *Fence) Opening() bool { return false }
 
+func (t *FigureOpen) Opening() bool { return true }
+
+func (t *FigureClose) Opening() bool { return false }
+
 func (t *Softbreak) Opening() bool { return false }
 
 func (t *Hardbreak) Opening() bool { return false }
@@ -466,6 +596,14 @@ func (t *Hr) Opening() bool { return false }
 
 func (t *Image) Opening() bool { return false }
 
+func (t *TaskCheckbox) Opening() bool { return false }
+
+func (t *IncludeOpen) Opening() bool { return true }
+
+func (t *IncludeClose) Opening() bool { return false }
+
+func (t *IncludeError) Opening() bool { return false }
+
 func (t *Inline) Opening() bool { return false }
 
 func (t *LinkOpen) Opening() bool { return true }
@@ -480,6 +618,10 @@ func (t *TableOpen) Opening() bool { return true }
 
 func (t *TableClose) Opening() bool { return false }
 
+func (t *CaptionOpen) Opening() bool { return true }
+
+func (t *CaptionClose) Opening() bool { return false }
+
 func (t *TheadOpen) Opening() bool { return true }
 
 func (t *TheadClose) Opening() bool { return false }
@@ -522,6 +664,10 @@ func (t *CodeBlock) Closing() bool { return false }
 
 func (t *CodeInline) Closing() bool { return false }
 
+func (t *ContainerOpen) Closing() bool { return false }
+
+func (t *ContainerClose) Closing() bool { return true }
+
 func (t *EmphasisOpen) Closing() bool { return false }
 
 func (t *EmphasisClose) Closing() bool { return true }
@@ -536,6 +682,10 @@ func (t *StrikethroughClose) Closing() bool { return true }
 
 func (t *Fence) Closing() bool { return false }
 
+func (t *FigureOpen) Closing() bool { return false }
+
+func (t *FigureClose) Closing() bool { return true }
+
 func (t *Softbreak) Closing() bool { return false }
 
 func (t *Hardbreak) Closing() bool { return false }
@@ -552,6 +702,14 @@ func (t *Hr) Closing() bool { return false }
 
 func (t *Image) Closing() bool { return false }
 
+func (t *TaskCheckbox) Closing() bool { return false }
+
+func (t *IncludeOpen) Closing() bool { return false }
+
+func (t *IncludeClose) Closing() bool { return true }
+
+func (t *IncludeError) Closing() bool { return false }
+
 func (t *Inline) Closing() bool { return false }
 
 func (t *LinkOpen) Closing() bool { return false }
@@ -566,6 +724,10 @@ func (t *TableOpen) Closing() bool { return false }
 
 func (t *TableClose) Closing() bool { return true }
 
+func (t *CaptionOpen) Closing() bool { return false }
+
+func (t *CaptionClose) Closing() bool { return true }
+
 func (t *TheadOpen) Closing() bool { return false }
 
 func (t *TheadClose) Closing() bool { return true }
@@ -608,6 +770,10 @@ func (t *CodeBlock) Block() bool { return true }
 
 func (t *CodeInline) Block() bool { return false }
 
+func (t *ContainerOpen) Block() bool { return true }
+
+func (t *ContainerClose) Block() bool { return true }
+
 func (t *EmphasisOpen) Block() bool { return false }
 
 func (t *EmphasisClose) Block() bool { return false }
@@ -622,6 +788,10 @@ func (t *StrikethroughClose) Block() bool { return false }
 
 func (t *Fence) Block() bool { return true }
 
+func (t *FigureOpen) Block() bool { return true }
+
+func (t *FigureClose) Block() bool { return true }
+
 func (t *Softbreak) Block() bool { return false }
 
 func (t *Hardbreak) Block() bool { return false }
@@ -638,6 +808,14 @@ func (t *Hr) Block() bool { return true }
 
 func (t *Image) Block() bool { return false }
 
+func (t *TaskCheckbox) Block() bool { return false }
+
+func (t *IncludeOpen) Block() bool { return true }
+
+func (t *IncludeClose) Block() bool { return true }
+
+func (t *IncludeError) Block() bool { return true }
+
 func (t *Inline) Block() bool { return false }
 
 func (t *LinkOpen) Block() bool { return false }
@@ -652,6 +830,10 @@ func (t *TableOpen) Block() bool { return true }
 
 func (t *TableClose) Block() bool { return true }
 
+func (t *CaptionOpen) Block() bool { return true }
+
+func (t *CaptionClose) Block() bool { return true }
+
 func (t *TheadOpen) Block() bool { return true }
 
 func (t *TheadClose) Block() bool { return true }
@@ -674,9 +856,9 @@ func (t *TdClose) Block() bool { return true }
 
 func (t *Text) Block() bool { return false }
 
-func (t *BlockquoteOpen) Tag() string { return "blockquote" }
+func (t *BlockquoteOpen) Tag() string { return blockquoteTag(t.Alert) }
 
-func (t *BlockquoteClose) Tag() string { return "blockquote" }
+func (t *BlockquoteClose) Tag() string { return blockquoteTag(t.Alert) }
 
 func (t *BulletListOpen) Tag() string { return "ul" }
 
@@ -694,6 +876,10 @@ func (t *CodeBlock) Tag() string { return "code" }
 
 func (t *CodeInline) Tag() string { return "code" }
 
+func (t *ContainerOpen) Tag() string { return "div" }
+
+func (t *ContainerClose) Tag() string { return "div" }
+
 func (t *EmphasisOpen) Tag() string { return "em" }
 
 func (t *EmphasisClose) Tag() string { return "em" }
@@ -708,6 +894,10 @@ func (t *StrikethroughClose) Tag() string { return "s" }
 
 func (t *Fence) Tag() string { return "code" }
 
+func (t *FigureOpen) Tag() string { return "figure" }
+
+func (t *FigureClose) Tag() string { return "figure" }
+
 func (t *Softbreak) Tag() string { return "br" }
 
 func (t *Hardbreak) Tag() string { return "br" }
@@ -724,6 +914,14 @@ func (t *Hr) Tag() string { return "hr" }
 
 func (t *Image) Tag() string { return "img" }
 
+func (t *TaskCheckbox) Tag() string { return "input" }
+
+func (t *IncludeOpen) Tag() string { return "" }
+
+func (t *IncludeClose) Tag() string { return "" }
+
+func (t *IncludeError) Tag() string { return "p" }
+
 func (t *Inline) Tag() string { return "" }
 
 func (t *LinkOpen) Tag() string { return "a" }
@@ -738,6 +936,10 @@ func (t *TableOpen) Tag() string { return "table" }
 
 func (t *TableClose) Tag() string { return "table" }
 
+func (t *CaptionOpen) Tag() string { return "caption" }
+
+func (t *CaptionClose) Tag() string { return "caption" }
+
 func (t *TheadOpen) Tag() string { return "thead" }
 
 func (t *TheadClose) Tag() string { return "thead" }
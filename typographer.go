@@ -0,0 +1,236 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TypographerPreset bundles the typographic conventions of a language.
+type TypographerPreset struct {
+	Quotes [4]rune // double/single quotes replacement pairs
+
+	// QuoteSpace is put inside the double quotes, e.g. a narrow no-break
+	// space in French: « mot ».
+	QuoteSpace string
+
+	// PunctSpace is put before each of the characters in SpacedPunct
+	// that ends a word, e.g. a narrow no-break space before ;:!? in French.
+	PunctSpace  string
+	SpacedPunct string
+
+	EnDash   string // replacement for --
+	EmDash   string // replacement for ---
+	Ellipsis string // replacement for ...
+}
+
+var defaultPreset = TypographerPreset{
+	Quotes:   [4]rune{'“', '”', '‘', '’'},
+	EnDash:   "–",
+	EmDash:   "—",
+	Ellipsis: "…",
+}
+
+var (
+	presetsMu sync.RWMutex
+	presets   = map[string]TypographerPreset{
+		"en": defaultPreset,
+		"fr": {
+			Quotes:      [4]rune{'«', '»', '“', '”'},
+			QuoteSpace:  "\u202f",
+			PunctSpace:  "\u202f",
+			SpacedPunct: ";:!?",
+			EnDash:      "–",
+			EmDash:      "—",
+			Ellipsis:    "…",
+		},
+		"de": {
+			Quotes:   [4]rune{'„', '“', '‚', '‘'},
+			EnDash:   "–",
+			EmDash:   "—",
+			Ellipsis: "…",
+		},
+		"ru": {
+			Quotes:   [4]rune{'«', '»', '„', '“'},
+			EnDash:   "–",
+			EmDash:   "—",
+			Ellipsis: "…",
+		},
+	}
+)
+
+// RegisterTypographerPreset makes the preset available to the
+// TypographerLocale option under the given name, replacing the preset
+// already registered under it, if any. Empty dash and ellipsis
+// replacements are taken from the English preset.
+func RegisterTypographerPreset(name string, p TypographerPreset) {
+	if p.EnDash == "" {
+		p.EnDash = defaultPreset.EnDash
+	}
+	if p.EmDash == "" {
+		p.EmDash = defaultPreset.EmDash
+	}
+	if p.Ellipsis == "" {
+		p.Ellipsis = defaultPreset.Ellipsis
+	}
+
+	presetsMu.Lock()
+	presets[strings.ToLower(name)] = p
+	presetsMu.Unlock()
+}
+
+// lookupTypographerPreset finds the preset for a locale such as "fr" or
+// "fr-CA", falling back from the region to the language.
+func lookupTypographerPreset(locale string) (TypographerPreset, bool) {
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+
+	if p, ok := presets[locale]; ok {
+		return p, true
+	}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		p, ok := presets[locale[:i]]
+		return p, ok
+	}
+	return TypographerPreset{}, false
+}
+
+func isSpaceRune(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u202f'
+}
+
+// applyPresetSpacing puts the spaces of the preset inside the double
+// quotes and before the spaced punctuation, replacing the ordinary spaces
+// already there. prev and next are the characters of the inline content
+// before and after s, or 0 at the start or the end of the content.
+func applyPresetSpacing(s string, prev, next rune, p *TypographerPreset) string {
+	if !strings.ContainsRune(s, p.Quotes[0]) && !strings.ContainsRune(s, p.Quotes[1]) &&
+		(p.SpacedPunct == "" || !strings.ContainsAny(s, p.SpacedPunct)) {
+		return s
+	}
+
+	text := []rune(s)
+	var buf strings.Builder
+	for i := 0; i < len(text); i++ {
+		r := text[i]
+		before, after := prev, next
+		if i > 0 {
+			before = text[i-1]
+		}
+		if i+1 < len(text) {
+			after = text[i+1]
+		}
+
+		switch {
+		case p.QuoteSpace != "" && r == p.Quotes[0]:
+			buf.WriteRune(r)
+			if i+1 < len(text) && after == ' ' {
+				// Replace the space.
+				buf.WriteString(p.QuoteSpace)
+				i++
+			} else if after != 0 && !isSpaceRune(after) {
+				buf.WriteString(p.QuoteSpace)
+			}
+			continue
+
+		case r == ' ' && p.QuoteSpace != "" && after == p.Quotes[1]:
+			buf.WriteString(p.QuoteSpace)
+			continue
+
+		case r == ' ' && p.PunctSpace != "" && after != 0 && strings.ContainsRune(p.SpacedPunct, after):
+			buf.WriteString(p.PunctSpace)
+			continue
+
+		case p.QuoteSpace != "" && r == p.Quotes[1]:
+			if before != 0 && !isSpaceRune(before) {
+				buf.WriteString(p.QuoteSpace)
+			}
+
+		case p.PunctSpace != "" && strings.ContainsRune(p.SpacedPunct, r):
+			ends := after == 0 || unicode.IsSpace(after) || after == p.Quotes[1] ||
+				strings.ContainsRune(p.SpacedPunct, after)
+			if ends && before != 0 && !unicode.IsSpace(before) && !strings.ContainsRune(p.SpacedPunct, before) {
+				buf.WriteString(p.PunctSpace)
+			}
+		}
+
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// inlineEdges returns the first and the last character of the content of
+// an inline token. Markup tokens such as emphasis or links have none.
+func inlineEdges(tok Token) (first, last rune, ok bool) {
+	var content string
+	switch tok := tok.(type) {
+	case *Text:
+		content = tok.Content
+	case *CodeInline:
+		content = tok.Content
+	case *Softbreak, *Hardbreak:
+		return ' ', ' ', true
+	}
+	if content == "" {
+		return 0, 0, false
+	}
+	first, _ = utf8.DecodeRuneInString(content)
+	last, _ = utf8.DecodeLastRuneInString(content)
+	return first, last, true
+}
+
+func rulePresetSpacing(s *stateCore) {
+	p := &s.md.Preset
+	if !s.md.Typographer || p.QuoteSpace == "" && p.PunctSpace == "" {
+		return
+	}
+
+	// The spacing depends on the characters around the quotes and the
+	// punctuation, which may be in the neighbouring tokens, as in *mot*!
+	var prevs, nexts []rune
+	for _, tok := range s.tokens {
+		tok, ok := tok.(*Inline)
+		if !ok {
+			continue
+		}
+		children := tok.Children
+		prevs = append(prevs[:0], make([]rune, len(children))...)
+		nexts = append(nexts[:0], make([]rune, len(children))...)
+		var r rune
+		for i, itok := range children {
+			prevs[i] = r
+			if _, last, ok := inlineEdges(itok); ok {
+				r = last
+			}
+		}
+		r = 0
+		for i := len(children) - 1; i >= 0; i-- {
+			nexts[i] = r
+			if first, _, ok := inlineEdges(children[i]); ok {
+				r = first
+			}
+		}
+
+		for i, itok := range children {
+			if itok, ok := itok.(*Text); ok {
+				itok.Content = applyPresetSpacing(itok.Content, prevs[i], nexts[i], p)
+			}
+		}
+	}
+}
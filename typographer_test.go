@@ -0,0 +1,51 @@
+package markdown
+
+import "testing"
+
+func TestTypographerLocale(t *testing.T) {
+	RegisterTypographerPreset("x-test", TypographerPreset{
+		Quotes: [4]rune{'<', '>', '[', ']'},
+		EmDash: "--",
+	})
+
+	for _, tt := range []struct {
+		locale string
+		tests  []renderTest
+	}{
+		{"en", []renderTest{
+			{`"a 'b' c" -- d`, "<p>“a ‘b’ c” – d</p>\n"},
+		}},
+		{"de", []renderTest{
+			{`"a 'b' c"`, "<p>„a ‚b‘ c“</p>\n"},
+		}},
+		{"ru", []renderTest{
+			{`"a 'b' c"`, "<p>«a „b“ c»</p>\n"},
+		}},
+		{"fr", []renderTest{
+			{`"mot" et "mot" ?`, "<p>«\u202fmot\u202f» et «\u202fmot\u202f»\u202f?</p>\n"},
+			{"«\u202fmot\u202f»", "<p>«\u202fmot\u202f»</p>\n"},
+			{"« mot »", "<p>«\u202fmot\u202f»</p>\n"},
+			{"`?` and [?](/x)", "<p><code>?</code> and <a href=\"/x\">?</a></p>\n"},
+			{"`a`!", "<p><code>a</code>\u202f!</p>\n"},
+			{"*x*!", "<p><em>x</em>\u202f!</p>\n"},
+			{"**[x](/x)**?", "<p><strong><a href=\"/x\">x</a></strong>\u202f?</p>\n"},
+			{"*x* !", "<p><em>x</em>\u202f!</p>\n"},
+			{"*x*!y", "<p><em>x</em>!y</p>\n"},
+			{"x!*y*", "<p>x!<em>y</em></p>\n"},
+			{"a\n!", "<p>a\n!</p>\n"},
+			{"«*mot*»", "<p>«\u202f<em>mot</em>\u202f»</p>\n"},
+			{"«`mot`» !", "<p>«\u202f<code>mot</code>\u202f»\u202f!</p>\n"},
+		}},
+		{"fr-CA", []renderTest{
+			{`Quoi? Oui: 10:30! Non!?`, "<p>Quoi\u202f? Oui\u202f: 10:30\u202f! Non\u202f!?</p>\n"},
+		}},
+		{"x-test", []renderTest{
+			{`"a" --- b`, "<p>&lt;a&gt; -- b</p>\n"},
+		}},
+		{"unknown", []renderTest{
+			{`"a"`, "<p>“a”</p>\n"},
+		}},
+	} {
+		runRenderTests(t, tt.tests, TypographerLocale(tt.locale))
+	}
+}
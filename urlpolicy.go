@@ -0,0 +1,116 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import (
+	"strings"
+
+	"github.com/opennota/html"
+)
+
+// URLContext tells a URL policy where the URL being checked comes from.
+type URLContext int
+
+const (
+	URLLink      URLContext = iota // link destination, wiki link or resolved reference
+	URLImage                       // image source
+	URLAutolink                    // <scheme:...> or <email> autolink
+	URLLinkify                     // URL found in plain text by the linkifier
+	URLReference                   // link reference definition
+	URLHTML                        // href, src or another URL attribute of sanitized raw HTML
+)
+
+// URLPolicy decides which link and image URLs are kept. A rejected URL is
+// not linked.
+//
+// A URL whose scheme is in DenySchemes is rejected. If AllowSchemes is not
+// empty, so is a URL whose scheme is not in it; relative URLs are always
+// allowed. A data: URL must also have one of the media types in DataTypes.
+// Finally, if Validate is set, the URL must pass it.
+//
+// The destinations of link reference definitions are checked as
+// URLReference, and again as URLLink or URLImage where they are used.
+type URLPolicy struct {
+	AllowSchemes []string
+	DenySchemes  []string
+	DataTypes    []string
+	Validate     func(url string, ctx URLContext) bool
+}
+
+// DefaultURLPolicy returns the policy used unless another one is set: it
+// rejects the file, javascript and vbscript schemes and the data: URLs
+// other than GIF, PNG, JPEG and WebP images.
+func DefaultURLPolicy() *URLPolicy {
+	return &URLPolicy{
+		DenySchemes: []string{"file", "javascript", "vbscript"},
+		DataTypes:   []string{"image/gif", "image/png", "image/jpeg", "image/webp"},
+	}
+}
+
+var defaultURLPolicy = DefaultURLPolicy()
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlScheme returns the lowercased scheme of url with the control
+// characters and spaces removed, or "" if url is relative.
+func urlScheme(url string) (scheme, rest string) {
+	str := html.ReplaceEntities(url)
+	str = strings.TrimSpace(str)
+	str = strings.ToLower(str)
+
+	i := strings.IndexByte(str, ':')
+	if i < 0 || strings.ContainsAny(str[:i], "/?#") {
+		return "", str
+	}
+	return removeSpecial(str[:i]), str[i+1:]
+}
+
+// Allows reports whether the policy allows url in the given context.
+func (p *URLPolicy) Allows(url string, ctx URLContext) bool {
+	scheme, rest := urlScheme(url)
+	if scheme != "" {
+		if containsFold(p.DenySchemes, scheme) {
+			return false
+		}
+		if len(p.AllowSchemes) > 0 && !containsFold(p.AllowSchemes, scheme) {
+			return false
+		}
+		if scheme == "data" {
+			end := strings.IndexAny(rest, ";,")
+			if end < 0 || !containsFold(p.DataTypes, strings.TrimSpace(rest[:end])) {
+				return false
+			}
+		}
+	}
+
+	if p.Validate != nil {
+		return p.Validate(url, ctx)
+	}
+	return true
+}
+
+func (m *Markdown) allowsURL(url string, ctx URLContext) bool {
+	p := m.URLPolicy
+	if p == nil {
+		p = defaultURLPolicy
+	}
+	return p.Allows(url, ctx)
+}
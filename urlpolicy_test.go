@@ -0,0 +1,65 @@
+package markdown
+
+import "testing"
+
+func TestURLPolicy(t *testing.T) {
+	trusted := DefaultURLPolicy()
+	trusted.DataTypes = append(trusted.DataTypes, "image/svg+xml")
+
+	untrusted := &URLPolicy{
+		AllowSchemes: []string{"http", "https", "mailto"},
+	}
+
+	imagesOnly := &URLPolicy{
+		Validate: func(url string, ctx URLContext) bool {
+			return ctx == URLImage || ctx == URLReference
+		},
+	}
+
+	runRenderTests(t, []renderTest{
+		{"![a](data:image/svg+xml;base64,PHN2Zz4=)", "<p>![a](data:image/svg+xml;base64,PHN2Zz4=)</p>\n"},
+		{"[a](JavaScript:x) [b](VBSCRIPT:x)", "<p>[a](JavaScript:x) [b](VBSCRIPT:x)</p>\n"},
+		{"[a](&#106;avascript:x) [b](javascript&colon;x)", "<p>[a](javascript:x) [b](javascript:x)</p>\n"},
+		{"<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+	}, ValidateURLs(nil), Linkify(true))
+
+	runRenderTests(t, []renderTest{
+		{"![a](data:image/svg+xml;base64,PHN2Zz4=)", "<p><img src=\"data:image/svg+xml;base64,PHN2Zz4=\" alt=\"a\"></p>\n"},
+	}, ValidateURLs(trusted), Linkify(true))
+
+	runRenderTests(t, []renderTest{
+		{"[a](javascript:x) [b](ftp://h/f) [c](/rel/a:b)", "<p>[a](javascript:x) [b](ftp://h/f) <a href=\"/rel/a:b\">c</a></p>\n"},
+		{"[a](https://x.org) <mailto:a@b.org> https://y.org", "<p><a href=\"https://x.org\">a</a> <a href=\"mailto:a@b.org\">mailto:a@b.org</a> <a href=\"https://y.org\">https://y.org</a></p>\n"},
+	}, ValidateURLs(untrusted), Linkify(true))
+
+	runRenderTests(t, []renderTest{
+		{"[a](/x) ![b](/y.png)", "<p>[a](/x) <img src=\"/y.png\" alt=\"b\"></p>\n"},
+		{"[a][r] ![b][r]\n\n[r]: /y.png", "<p>[a][r] <img src=\"/y.png\" alt=\"b\"></p>\n"},
+		{"<https://x.org> www.y.org", "<p>&lt;https://x.org&gt; www.y.org</p>\n"},
+	}, ValidateURLs(imagesOnly), Linkify(true))
+}
+
+func TestURLPolicyContext(t *testing.T) {
+	var got []URLContext
+	policy := &URLPolicy{
+		Validate: func(url string, ctx URLContext) bool {
+			got = append(got, ctx)
+			return true
+		},
+	}
+	src := "[a](/a) ![b](/b) <http://c.org> http://d.org"
+	if _, err := render(src, ValidateURLs(policy), Linkify(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []URLContext{URLLink, URLImage, URLAutolink, URLLinkify}
+	if len(got) != len(want) {
+		t.Fatalf("contexts = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("contexts = %v, want %v", got, want)
+			break
+		}
+	}
+}
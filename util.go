@@ -16,18 +16,22 @@ package markdown
 import (
 	"bytes"
 	"net/url"
-	"regexp"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 
-	"github.com/opennota/html"
-	"github.com/opennota/urlesc"
+	"github.com/opennota/byteutil"
 )
 
-var mdpunct [256]bool
+var (
+	mdpunct [256]bool
+	urlSafe [256]bool
+)
 
 func init() {
+	for _, b := range "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz;/?:@&=+$,-_.!~*'()#" {
+		urlSafe[b] = true
+	}
 	for _, b := range "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~" {
 		mdpunct[b] = true
 	}
@@ -40,27 +44,50 @@ func isMarkdownPunct(r rune) bool {
 	return mdpunct[r]
 }
 
+// isPunct reports whether r is an ASCII or Unicode punctuation character,
+// which in CommonMark includes the Unicode symbols.
+func isPunct(r rune) bool {
+	return isMarkdownPunct(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// normalizeLink percent-encodes the bytes of a link destination that may
+// not appear in a URL, leaving the valid escapes as they are.
 func normalizeLink(rawurl string) string {
-	parsed, err := url.Parse(rawurl)
-	if err != nil {
-		return ""
+	const hex = "0123456789ABCDEF"
+	var buf []byte
+	for i := 0; i < len(rawurl); i++ {
+		b := rawurl[i]
+		if urlSafe[b] || b == '%' && isEscape(rawurl[i+1:]) {
+			if buf != nil {
+				buf = append(buf, b)
+			}
+			continue
+		}
+		if buf == nil {
+			buf = append(make([]byte, 0, len(rawurl)+16), rawurl[:i]...)
+		}
+		buf = append(buf, '%', hex[b>>4], hex[b&15])
+	}
+	if buf == nil {
+		return rawurl
 	}
+	return string(buf)
+}
 
-	return urlesc.Escape(parsed)
+// isEscape reports whether s begins with the two hexadecimal digits of a
+// percent escape.
+func isEscape(s string) bool {
+	return len(s) > 1 && isHexDigit(byteutil.ByteToLower(s[0])) && isHexDigit(byteutil.ByteToLower(s[1]))
 }
 
 func normalizeLinkText(text string) string {
-	unescaped, _ := url.QueryUnescape(text)
+	unescaped, _ := url.PathUnescape(text)
 	if unescaped == "" || !utf8.ValidString(unescaped) {
 		return text
 	}
 	return unescaped
 }
 
-var badProtos = []string{"file", "javascript", "vbscript"}
-
-var rGoodData = regexp.MustCompile(`^data:image/(gif|png|jpeg|webp);`)
-
 func removeSpecial(s string) string {
 	i := 0
 	for i < len(s) && !(s[i] <= 0x20 || s[i] == 0x7f) {
@@ -81,24 +108,7 @@ func removeSpecial(s string) string {
 }
 
 func validateLink(url string) bool {
-	str := html.ReplaceEntities(url)
-	str = strings.TrimSpace(str)
-	str = strings.ToLower(str)
-
-	if strings.IndexByte(str, ':') >= 0 {
-		proto := strings.SplitN(str, ":", 2)[0]
-		proto = removeSpecial(proto)
-		for _, p := range badProtos {
-			if proto == p {
-				return false
-			}
-		}
-		if proto == "data" && !rGoodData.MatchString(str) {
-			return false
-		}
-	}
-
-	return true
+	return defaultURLPolicy.Allows(url, URLLink)
 }
 
 func unescapeAll(s string) string {
@@ -112,7 +122,7 @@ func unescapeAll(s string) string {
 				break
 			}
 		} else if b == '&' {
-			if _, n := html.ParseEntity(s[i:]); n > 0 {
+			if _, n := parseEntity(s[i:]); n > 0 {
 				anyChanges = true
 				break
 			}
@@ -145,7 +155,7 @@ func unescapeAll(s string) string {
 				continue
 			}
 		} else if b == '&' {
-			if e, n := html.ParseEntity(s[i:]); n > 0 {
+			if e, n := parseEntity(s[i:]); n > 0 {
 				if len(e) > n && len(buf) == len(s) {
 					newBuf := make([]byte, cap(buf)*2)
 					copy(newBuf[:j], buf)
@@ -164,62 +174,15 @@ func unescapeAll(s string) string {
 	return string(buf[:j])
 }
 
+// normalizeInlineCode turns the line endings of a code span into spaces
+// and strips one space from both ends if it both begins and ends with a
+// space but is not all spaces.
 func normalizeInlineCode(s string) string {
-	if s == "" {
-		return ""
-	}
-
-	byteFuckery := false
-	i := 0
-	for i < len(s)-1 {
-		b := s[i]
-		if b == '\n' {
-			byteFuckery = true
-			break
-		}
-		if b == ' ' {
-			i++
-			b = s[i]
-			if b == ' ' || b == '\n' {
-				i--
-				byteFuckery = true
-				break
-			}
-		}
-		i++
-	}
-
-	if !byteFuckery {
-		return strings.TrimSpace(s)
+	s = strings.Replace(s, "\n", " ", -1)
+	if len(s) > 1 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.Trim(s, " ") != "" {
+		s = s[1 : len(s)-1]
 	}
-
-	buf := make([]byte, len(s))
-	copy(buf[:i], s)
-	buf[i] = ' '
-	i++
-	j := i
-	lastSpace := true
-	for i < len(s) {
-		b := s[i]
-		switch b {
-		case ' ', '\n':
-			if lastSpace {
-				break
-			}
-
-			buf[j] = ' '
-			lastSpace = true
-			j++
-		default:
-			buf[j] = b
-			lastSpace = false
-			j++
-		}
-
-		i++
-	}
-
-	return string(bytes.TrimSpace(buf[:j]))
+	return s
 }
 
 func normalizeReference(s string) string {
@@ -234,7 +197,14 @@ func normalizeReference(s string) string {
 			continue
 		}
 
-		buf.WriteRune(unicode.To(unicode.LowerCase, r))
+		// Fold the case as markdown-it does, by lowercasing the
+		// uppercased label, which maps ß and ẞ to "ss".
+		r = unicode.ToLower(unicode.ToUpper(r))
+		if r == 'ß' {
+			buf.WriteString("ss")
+		} else {
+			buf.WriteRune(r)
+		}
 		lastSpace = false
 	}
 
@@ -35,17 +35,19 @@ func TestNormalizeInlineCode(t *testing.T) {
 	}
 	testCases := []testCase{
 		{"", ""},
-		{"    ", ""},
-		{"\n", ""},
-		{"\n\n", ""},
+		{"    ", "    "},
+		{"\n", " "},
+		{"\n\n", "  "},
 		{"\nfoo\n", "foo"},
 		{"foo bar", "foo bar"},
 		{"foo\nbar", "foo bar"},
-		{"foo\n\nbar", "foo bar"},
-		{"foo  bar", "foo bar"},
-		{"foo  \nbar", "foo bar"},
-		{"  foo  ", "foo"},
-		{"    foo   bar\n  baz\n  foo\n\n", "foo bar baz foo"},
+		{"foo\n\nbar", "foo  bar"},
+		{"foo  bar", "foo  bar"},
+		{"foo  \nbar", "foo   bar"},
+		{"  foo  ", " foo "},
+		{" foo", " foo"},
+		{" `` ", "``"},
+		{"    foo   bar\n  baz\n  foo\n\n", "   foo   bar   baz   foo "},
 	}
 	for _, tc := range testCases {
 		got := normalizeInlineCode(tc.in)
@@ -116,10 +118,14 @@ func TestNormalizeLink(t *testing.T) {
 	}
 	testCases := []testCase{
 		{"", ""},
-		{"http://google.com/s%65arch", "http://google.com/search"},
+		{"http://google.com/s%65arch", "http://google.com/s%65arch"},
 		{"http://google.com/search?query=%5B%5D", "http://google.com/search?query=%5B%5D"},
+		{"http://google.com/search?query=[]", "http://google.com/search?query=%5B%5D"},
 		{"//google.com", "//google.com"},
-		{"http://%XX", ""},
+		{"http://%XX", "http://%25XX"},
+		{"foo):", "foo):"},
+		{"/url\\bar*baz", "/url%5Cbar*baz"},
+		{"http://例え.jp/ä", "http://%E4%BE%8B%E3%81%88.jp/%C3%A4"},
 	}
 	for _, tc := range testCases {
 		got := normalizeLink(tc.in)
@@ -161,6 +167,10 @@ func TestNormalizeReference(t *testing.T) {
 		{"ref", "ref"},
 		{"REF", "ref"},
 		{"r\u00a0e\u00a0f", "r e f"},
+		{"ẞ", "ss"},
+		{"Straße", "strasse"},
+		{"ΣΑΣ", "σασ"},
+		{"σας", "σασ"},
 	}
 	for _, tc := range testCases {
 		got := normalizeReference(tc.in)
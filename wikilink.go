@@ -0,0 +1,105 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package markdown
+
+import "strings"
+
+// WikiLinkResolver maps the page and the optional section of a
+// [[page#section|label]] link to an URL. An empty href means that the text
+// is not a wiki link; exists is false for pages that are yet to be written.
+type WikiLinkResolver func(page, section string) (href string, exists bool)
+
+func parseWikiLink(s string) (page, section, label string, end int) {
+	if len(s) < 5 || s[0] != '[' || s[1] != '[' {
+		return
+	}
+
+	closing := strings.Index(s, "]]")
+	if closing < 0 {
+		return
+	}
+	inner := s[2:closing]
+	if strings.ContainsAny(inner, "[]\n") {
+		return
+	}
+
+	target := inner
+	if i := strings.IndexByte(inner, '|'); i >= 0 {
+		target = inner[:i]
+		label = strings.TrimSpace(inner[i+1:])
+	}
+	target = strings.TrimSpace(target)
+	if label == "" {
+		label = target
+	}
+
+	page = target
+	if i := strings.IndexByte(target, '#'); i >= 0 {
+		page = strings.TrimSpace(target[:i])
+		section = strings.TrimSpace(target[i+1:])
+	}
+	if page == "" && section == "" {
+		return "", "", "", 0
+	}
+
+	return page, section, label, closing + 2
+}
+
+func ruleWikiLink(s *stateInline, silent bool) (_ bool) {
+	resolve := s.md.WikiLinks
+	if resolve == nil {
+		return
+	}
+
+	pos := s.pos
+	src := s.src[:s.posMax]
+
+	if pos+4 >= len(src) || src[pos] != '[' || src[pos+1] != '[' {
+		return
+	}
+
+	page, section, label, n := parseWikiLink(src[pos:])
+	if n == 0 {
+		return
+	}
+
+	// [[foo]](url) and [[foo]][ref] are regular links to be handled by
+	// ruleLink.
+	if end := pos + n; end < len(src) && (src[end] == '(' || src[end] == '[') {
+		return
+	}
+
+	href, exists := resolve(page, section)
+	if href == "" {
+		return
+	}
+	href = normalizeLink(href)
+	if !s.md.allowsURL(href, URLLink) {
+		return
+	}
+
+	if !silent {
+		tok := &LinkOpen{Href: s.md.rewriteURL(href, URLLink)}
+		if !exists {
+			tok.Class = "new"
+		}
+		s.pushOpeningToken(tok)
+		s.pushToken(&Text{Content: label})
+		s.pushClosingToken(&LinkClose{})
+	}
+
+	s.pos += n
+
+	return true
+}
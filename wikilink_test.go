@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseWikiLink(t *testing.T) {
+	type testCase struct {
+		in      string
+		page    string
+		section string
+		label   string
+		end     int
+	}
+	testCases := []testCase{
+		{"", "", "", "", 0},
+		{"[[]]", "", "", "", 0},
+		{"[[ ]]", "", "", "", 0},
+		{"[[Page]]", "Page", "", "Page", 8},
+		{"[[Page Name|label]] tail", "Page Name", "", "label", 19},
+		{"[[Page#Section]]", "Page", "Section", "Page#Section", 16},
+		{"[[#Section|here]]", "", "Section", "here", 17},
+		{"[[a [b] c]]", "", "", "", 0},
+		{"[[a\nb]]", "", "", "", 0},
+		{"[[unclosed", "", "", "", 0},
+	}
+	for _, tc := range testCases {
+		page, section, label, end := parseWikiLink(tc.in)
+		if page != tc.page || section != tc.section || label != tc.label || end != tc.end {
+			t.Errorf("parseWikiLink(%q) = %q, %q, %q, %d, want %q, %q, %q, %d",
+				tc.in, page, section, label, end, tc.page, tc.section, tc.label, tc.end)
+		}
+	}
+}
+
+func TestWikiLinks(t *testing.T) {
+	resolve := func(page, section string) (string, bool) {
+		href := "/wiki/" + url.PathEscape(page)
+		if section != "" {
+			href += "#" + section
+		}
+		return href, page != "Missing"
+	}
+
+	runRenderTests(t, []renderTest{
+		{"see [[Home]]", "<p>see <a href=\"/wiki/Home\">Home</a></p>\n"},
+		{"[[Home|start here]]", "<p><a href=\"/wiki/Home\">start here</a></p>\n"},
+		{"[[Home#Intro]]", "<p><a href=\"/wiki/Home#Intro\">Home#Intro</a></p>\n"},
+		{"[[Missing]]", "<p><a href=\"/wiki/Missing\" class=\"new\">Missing</a></p>\n"},
+		{"[[Home]](/url)", "<p><a href=\"/url\">[Home]</a></p>\n"},
+		{"[[foo]][ref]\n\n[ref]: /ref", "<p><a href=\"/ref\">[foo]</a></p>\n"},
+		{"`[[Home]]`", "<p><code>[[Home]]</code></p>\n"},
+		{"\\[[Home]]", "<p>[[Home]]</p>\n"},
+		{"[[ Home ]] [[a|b|c]]", "<p><a href=\"/wiki/Home\">Home</a> <a href=\"/wiki/a\">b|c</a></p>\n"},
+		{"[[<script>]]", "<p><a href=\"/wiki/%3Cscript%3E\">&lt;script&gt;</a></p>\n"},
+		{"[[Home|*em*]]", "<p><a href=\"/wiki/Home\">*em*</a></p>\n"},
+	}, WikiLinks(resolve))
+
+	runRenderTests(t, []renderTest{
+		{"[[Home]]", "<p>[[Home]]</p>\n"},
+	})
+}